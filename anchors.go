@@ -0,0 +1,83 @@
+package bookie
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlIDPattern matches id attributes in raw HTML embedded in markdown.
+var htmlIDPattern = regexp.MustCompile(`\bid\s*=\s*["']([^"']+)["']`)
+
+// chapterAnchor returns the anchor name that targets a chapter's opening
+// page, derived from its directory name (e.g., "Episode03" -> "episode03").
+//
+// Parameters:
+//   - path: Chapter directory path
+//
+// Returns:
+//   - string: Lowercase anchor name
+func chapterAnchor(path string) string {
+	return strings.ToLower(filepath.Base(path))
+}
+
+// collectHTMLAnchors records the id attributes found in a raw HTML
+// fragment as known anchors.
+//
+// Parameters:
+//   - fragment: Raw HTML from a markdown HTML block or span
+func (bc *BookCompiler) collectHTMLAnchors(fragment string) {
+	for _, match := range htmlIDPattern.FindAllStringSubmatch(fragment, -1) {
+		bc.knownAnchors[match[1]] = true
+	}
+}
+
+// anchorLink returns the gofpdf internal link for an anchor, creating it
+// on first use. The link destination is set when the anchor is rendered.
+//
+// Parameters:
+//   - anchor: Anchor name without the leading "#"
+//
+// Returns:
+//   - int: gofpdf internal link identifier
+func (bc *BookCompiler) anchorLink(anchor string) int {
+	if link, ok := bc.anchorLinks[anchor]; ok {
+		return link
+	}
+	link := bc.pdf.AddLink()
+	bc.anchorLinks[anchor] = link
+	return link
+}
+
+// registerAnchor points the anchor's internal link at the current position.
+//
+// Parameters:
+//   - anchor: Anchor name without the leading "#"
+func (bc *BookCompiler) registerAnchor(anchor string) {
+	bc.pdf.SetLink(bc.anchorLink(anchor), -1, -1)
+}
+
+// renderInternalLink renders a fragment link ("#anchor") as an internal
+// jump to a heading, figure, or chapter. Anchors that were not found during
+// the first pass are logged and rendered as plain text.
+//
+// Parameters:
+//   - n: Anchor element node
+//   - anchor: Target anchor name without the leading "#"
+//
+// Returns:
+//   - error: Any rendering errors encountered
+func (bc *BookCompiler) renderInternalLink(n *html.Node, anchor string) error {
+	if !bc.knownAnchors[anchor] {
+		bc.logWarning("Unresolved anchor #%s in %s", anchor, bc.currentFile)
+		return bc.renderChildren(n)
+	}
+
+	previous := bc.linkID
+	bc.linkID = bc.anchorLink(anchor)
+	err := bc.renderChildren(n)
+	bc.linkID = previous
+	return err
+}
@@ -2,19 +2,40 @@ package bookie
 
 import (
 	"fmt"
-	"io/ioutil"
+	"math"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/russross/blackfriday/v2"
 )
 
+// whitespaceRun matches consecutive whitespace characters.
+var whitespaceRun = regexp.MustCompile(`\s+`)
+
+// asciiTypography replaces typographic characters, such as the curly
+// quotes and dashes blackfriday's smartypants produces, with their plain
+// equivalents in the core fonts.
+var asciiTypography = strings.NewReplacer(
+	"\u201C", `"`, "\u201D", `"`, "\u201E", `"`, // double quotes
+	"\u2018", "'", "\u2019", "'", "\u201A", "'", // single quotes and apostrophe
+	"\u2032", "'", "\u2033", `"`, // primes
+	"\u2013", "-", "\u2014", "-", "\u2011", "-", "\u2212", "-", // dashes, non-breaking hyphen, minus
+	"\u2026", "...", // ellipsis
+	"\u00BD", "1/2", "\u00BC", "1/4", "\u00BE", "3/4", // fractions
+	"\u00A0", " ", "\u2009", " ", "\u202F", " ", // non-breaking and thin spaces
+)
+
 // NewBookCompiler creates a new instance of BookCompiler
 func NewBookCompiler(rootDir, outputPath string) *BookCompiler {
 	bc := &BookCompiler{
 		RootDir:     rootDir,
 		OutputPath:  outputPath,
 		imageCache:  make(map[string]bool),
+		newPDF:      newGofpdf,
 		chapterFont: "Arial",
 		textFont:    "Times",
 		pageNumbers: true,
@@ -23,6 +44,51 @@ func NewBookCompiler(rootDir, outputPath string) *BookCompiler {
 		pageHeight:  297, // A4 height in mm
 		margin:      20,
 		tocLevels:   make(map[int]TextStyle),
+
+		pageNumberFormat:     defaultPageNumFormat,
+		frontMatterNumbering: FrontMatterArabic,
+		frontMatterInToC:     true,
+		indexTitle:           defaultIndexTitle,
+		linkColor:            rgbColor{0, 0, 255},
+		highlightColor:       rgbColor{255, 255, 0},
+		tableZebraColor:      rgbColor{zebraFillGray, zebraFillGray, zebraFillGray},
+		tableBorderStyle:     TableBorderGrid,
+		tableTextSize:        tableFontSize,
+		compression:          true,
+		blockquoteBarWidth:   defaultBlockquoteBar,
+		blockquoteBarColor:   rgbColor{180, 180, 180},
+		widowOrphanControl:   true,
+		lineSpacing:          1.0,
+		paragraphSpacing:     defaultParagraphSpacing,
+		markdownExtensions:   defaultMarkdownExtensions,
+		keepWithNextLines:    defaultKeepWithNext,
+		thematicBreakStyle:   ThematicBreakRule,
+		textDirection:        TextDirectionLTR,
+		fileSeparator:        FileSeparatorSpace,
+		chapterTitlePosition: ChapterTitleTop,
+		chapterSubtitles:     true,
+		tocTitleStyle: TextStyle{
+			Style:     fontStyleBold,
+			Size:      chapterTitleSize,
+			Alignment: AlignLeft,
+		},
+		pageNumberStyle: TextStyle{
+			FontFamily: pageNumFont,
+			Style:      pageNumStyle,
+			Size:       pageNumSize,
+			Alignment:  AlignCenter,
+		},
+		watermarkOpacity: defaultWatermarkOpacity,
+		headerVerso:      defaultHeaderVerso,
+		headerRecto:      defaultHeaderRecto,
+
+		generateToCPage: true,
+		pageBreakBefore: map[int]bool{1: true},
+		headingStyles:   make(map[int]headingStyle),
+		captionLabels:   make(map[string]string),
+		remoteImages:    make(map[string]string),
+		contents:        make(map[string][]byte),
+		contentImages:   make(map[string]string),
 	}
 
 	// Configure ToC styles
@@ -30,6 +96,17 @@ func NewBookCompiler(rootDir, outputPath string) *BookCompiler {
 	bc.tocLevels[2] = TextStyle{FontFamily: "Arial", Style: "", Size: 12}  // Major sections
 	bc.tocLevels[3] = TextStyle{FontFamily: "Arial", Style: "", Size: 10}  // Subsections
 
+	// Configure heading styles; an empty font family uses the chapter font
+	headingSizes := []float64{24, 20, 16, 14, 14, 14}
+	headingSpacing := []float64{20, 35, 25, 18, 18, 18}
+	for i, size := range headingSizes {
+		bc.headingStyles[i+1] = headingStyle{
+			text:        TextStyle{Style: fontStyleBold, Size: size},
+			spaceBefore: headingSpacing[i],
+			spaceAfter:  defaultHeadingSpaceAfter,
+		}
+	}
+
 	return bc
 }
 
@@ -38,20 +115,57 @@ func (bc *BookCompiler) collectToCEntries() error {
 	if err != nil {
 		return err
 	}
+	bc.hasIndexTerms = false
+	bc.knownAnchors = make(map[string]bool)
 
 	// Add ToC page(s)
-	bc.pdf.AddPage()
+	if bc.generateToCPage {
+		bc.pdf.AddPage()
+	}
+
+	for _, section := range bc.frontMatter {
+		bc.pdf.AddPage()
+		entries := len(bc.toc)
+		bc.toc = append(bc.toc, ToCEntry{
+			Title:   section.title,
+			Level:   1,
+			PageNum: bc.pdf.PageNo(),
+		})
+		if err := bc.collectMarkdownHeadings(section.path); err != nil {
+			return err
+		}
+		// Front matter left out of the ToC is still scanned for index
+		// markers and anchors
+		if !bc.frontMatterInToC {
+			bc.toc = bc.toc[:entries]
+		}
+	}
 
 	for _, chapter := range chapters {
+		if chapter.Meta[coverField] != "" {
+			bc.pdf.AddPage()
+		}
 		bc.pdf.AddPage()
+		if bc.bodyStartPage == 0 {
+			bc.bodyStartPage = bc.pdf.PageNo()
+		}
 		chapterName := filepath.Base(chapter.Path)
+		if chapter.Title != "" {
+			chapterName = chapter.Title
+		}
+		bc.knownAnchors[chapterAnchor(chapter.Path)] = true
 
 		// Add chapter to ToC
-		bc.toc = append(bc.toc, ToCEntry{
+		entry := ToCEntry{
 			Title:   chapterName,
 			Level:   1,
 			PageNum: bc.pdf.PageNo(),
-		})
+			Anchor:  chapterAnchor(chapter.Path),
+		}
+		if bc.tocSummaries {
+			entry.Summary = bc.chapterSummary(chapter)
+		}
+		bc.toc = append(bc.toc, entry)
 
 		// Collect subheadings from markdown files
 		for _, file := range chapter.Files {
@@ -61,27 +175,72 @@ func (bc *BookCompiler) collectToCEntries() error {
 		}
 	}
 
+	for _, section := range bc.backMatter {
+		bc.pdf.AddPage()
+		bc.toc = append(bc.toc, ToCEntry{
+			Title:   section.title,
+			Level:   1,
+			PageNum: bc.pdf.PageNo(),
+		})
+		bc.currentChapter = section
+		bc.headingCounts = [7]int{}
+		if err := bc.collectMarkdownHeadings(section.path); err != nil {
+			return err
+		}
+	}
+
+	if bc.hasIndexTerms {
+		bc.pdf.AddPage()
+		bc.toc = append(bc.toc, ToCEntry{
+			Title:   bc.indexTitle,
+			Level:   1,
+			PageNum: bc.pdf.PageNo(),
+		})
+	}
+
+	bc.tocBodyStartPage = bc.bodyStartPage
 	return nil
 }
 
 func (bc *BookCompiler) collectMarkdownHeadings(file string) error {
-	content, err := ioutil.ReadFile(file)
+	content, err := bc.readFile(file)
 	if err != nil {
 		return err
 	}
+	_, content = splitFrontMatter(content)
 
 	// Parse markdown and extract headings
 	renderer := blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{})
-	parser := blackfriday.New(blackfriday.WithRenderer(renderer))
+	parser := blackfriday.New(blackfriday.WithRenderer(renderer),
+		blackfriday.WithExtensions(bc.markdownExtensions))
 	ast := parser.Parse(content)
 
+	// The chapter or section title is the level 1 heading above the file
+	level := 1
 	ast.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if entering && (node.Type == blackfriday.HTMLBlock || node.Type == blackfriday.HTMLSpan) {
+			if _, ok := parseIndexMarker(string(node.Literal)); ok {
+				bc.hasIndexTerms = true
+			}
+			bc.collectHTMLAnchors(string(node.Literal))
+		}
+		if entering && node.Type == blackfriday.Heading && node.HeadingID != "" {
+			bc.knownAnchors[node.HeadingID] = true
+		}
+		if entering && node.Type == blackfriday.Heading {
+			bc.checkHeadingLevel(file, getString(node), level, node.Level)
+			level = node.Level
+		}
 		if entering && node.Type == blackfriday.Heading && node.Level > 1 {
 			title := getString(node)
+			if number := bc.appendixHeadingNumber(node.Level); number != "" {
+				title = number + " " + title
+			}
 			bc.toc = append(bc.toc, ToCEntry{
 				Title:   title,
 				Level:   node.Level,
 				PageNum: bc.pdf.PageNo(),
+				Anchor:  node.HeadingID,
 			})
 		}
 		return blackfriday.GoToNext
@@ -90,12 +249,31 @@ func (bc *BookCompiler) collectMarkdownHeadings(file string) error {
 	return nil
 }
 
+// checkHeadingLevel warns about a heading more than one level below the
+// heading before it, such as an h3 directly after an h1, which leaves a
+// gap in the ToC indentation and the PDF bookmark tree.
+//
+// Parameters:
+//   - file: Markdown file containing the heading
+//   - title: Text of the heading
+//   - previous: Level of the preceding heading
+//   - level: Level of the heading
+func (bc *BookCompiler) checkHeadingLevel(file, title string, previous, level int) {
+	if level > previous+1 {
+		bc.logWarning("Heading %q in %s skips from h%d to h%d", title, file, previous, level)
+	}
+}
+
 func (bc *BookCompiler) generateToC() {
 	bc.pdf.AddPage()
 
 	// Add ToC title
-	bc.pdf.SetFont(bc.chapterFont, "B", 24)
-	bc.pdf.Cell(0, 10, bc.tocTitle)
+	titleStyle := bc.tocTitleStyle
+	if titleStyle.FontFamily == "" {
+		titleStyle.FontFamily = bc.chapterFont
+	}
+	bc.setFont(titleStyle.FontFamily, titleStyle.Style, titleStyle.Size)
+	bc.cellWithFallback(0, 10, bc.normalizeText(bc.tocTitle), 0, titleStyle.Alignment, 0)
 	bc.pdf.Ln(20)
 
 	// Calculate width for different columns
@@ -107,29 +285,38 @@ func (bc *BookCompiler) generateToC() {
 	for _, entry := range bc.toc {
 		// Get style for current level
 		style := bc.tocLevels[entry.Level]
-		bc.pdf.SetFont(style.FontFamily, style.Style, style.Size)
+		bc.setFont(style.FontFamily, style.Style, style.Size)
 
 		// Calculate indentation
 		indent := float64(entry.Level-1) * 10
 		bc.pdf.SetX(bc.margin + indent)
 
+		// Link entries to their chapter or heading destination
+		link := 0
+		if entry.Anchor != "" {
+			link = bc.anchorLink(entry.Anchor)
+		}
+
 		// Add entry text with dots
-		title := entry.Title
+		title := bc.normalizeText(entry.Title)
 		dots := "..."
-		bc.pdf.CellFormat(
-			titleWidth-indent,
-			8,
-			title,
-			"", 0, "L", false, 0, "",
-		)
+		bc.cellWithFallback(titleWidth-indent, 8, title, 0, AlignLeft, link)
 
 		// Add page number right-aligned
 		bc.pdf.CellFormat(
 			pageNumWidth,
 			8,
-			fmt.Sprintf("%s %d", dots, entry.PageNum),
-			"", 1, "R", false, 0, "",
+			fmt.Sprintf("%s %s", dots, bc.pageLabel(entry.PageNum, bc.tocBodyStartPage)),
+			"", 1, "R", false, link, "",
 		)
+
+		// Add the chapter summary in smaller text beneath the entry
+		if entry.Summary != "" {
+			bc.setFont(style.FontFamily, fontStyleItalic, tocSummarySize)
+			bc.pdf.SetX(bc.margin + indent)
+			summary := bc.fitText(bc.normalizeText(entry.Summary), titleWidth-indent)
+			bc.cellWithFallback(titleWidth-indent, tocSummaryLineHeight, summary, 1, AlignLeft, 0)
+		}
 	}
 }
 
@@ -138,34 +325,853 @@ func (bc *BookCompiler) SetPageNumbers(enable bool) {
 	bc.pageNumbers = enable
 }
 
+// SetPageNumberFormat sets the page number text. The first %d is replaced
+// by the page number and an optional second %d by the total page count,
+// e.g. "%d", "- %d -", or "Page %d of %d". Defaults to "Page %d".
+func (bc *BookCompiler) SetPageNumberFormat(format string) {
+	bc.pageNumberFormat = format
+}
+
+// SetPageNumberPosition sets the horizontal alignment of page numbers:
+// AlignLeft, AlignCenter (default), or AlignRight.
+func (bc *BookCompiler) SetPageNumberPosition(align string) {
+	bc.pageNumberStyle.Alignment = align
+}
+
+// SetPageNumberFont sets the font family, style, and size of page numbers.
+// Defaults to Arial italic 8pt.
+func (bc *BookCompiler) SetPageNumberFont(family, style string, size float64) {
+	bc.pageNumberStyle.FontFamily = family
+	bc.pageNumberStyle.Style = style
+	bc.pageNumberStyle.Size = size
+}
+
+// SetFrontMatterNumbering selects page numbering for the pages preceding
+// the first chapter: FrontMatterArabic (default) or FrontMatterRoman.
+func (bc *BookCompiler) SetFrontMatterNumbering(style string) {
+	bc.frontMatterNumbering = style
+}
+
+// pageLabel returns the displayed page number for a physical page.
+//
+// Parameters:
+//   - page: Physical PDF page number
+//   - bodyStart: Physical page on which the first chapter opens, or 0 if
+//     it has not been reached yet
+//
+// Returns:
+//   - string: Roman numeral for front matter pages in FrontMatterRoman
+//     mode, otherwise the Arabic page number
+func (bc *BookCompiler) pageLabel(page, bodyStart int) string {
+	if bc.frontMatterNumbering != FrontMatterRoman {
+		return strconv.Itoa(page)
+	}
+	if bodyStart == 0 || page < bodyStart {
+		return toRoman(page)
+	}
+	return strconv.Itoa(page - bodyStart + 1)
+}
+
+// AddFrontMatter appends a titled section, such as a preface or dedication,
+// rendered from a markdown file between the table of contents and the
+// first chapter. Front matter is not part of episode numbering.
+//
+// Parameters:
+//   - title: Section title shown on its opening page and in the ToC
+//   - markdownPath: Path to the markdown file with the section content
+func (bc *BookCompiler) AddFrontMatter(title, markdownPath string) {
+	bc.frontMatter = append(bc.frontMatter, matterSection{title: title, path: markdownPath})
+}
+
+// AddBackMatter appends an unnumbered section, such as a glossary or
+// bibliography, rendered from a markdown file after the last chapter.
+// Back matter sections are listed in the table of contents and the PDF
+// bookmarks.
+//
+// Parameters:
+//   - title: Section title shown on its opening page and in the ToC
+//   - markdownPath: Path to the markdown file with the section content
+func (bc *BookCompiler) AddBackMatter(title, markdownPath string) {
+	bc.backMatter = append(bc.backMatter, matterSection{title: title, path: markdownPath})
+}
+
+// AddAppendix appends a lettered back matter section. Appendices are
+// titled "Appendix A: <title>", "Appendix B: <title>", and so on in the
+// order they are added, and their h2-h6 headings are numbered after the
+// letter: "A.1", "A.2", "A.2.1".
+//
+// Parameters:
+//   - title: Appendix title without the letter prefix
+//   - markdownPath: Path to the markdown file with the appendix content
+func (bc *BookCompiler) AddAppendix(title, markdownPath string) {
+	letter := appendixLetter(bc.appendixCount)
+	bc.appendixCount++
+	bc.backMatter = append(bc.backMatter, matterSection{
+		title:  fmt.Sprintf("%s %s: %s", appendixPrefix, letter, title),
+		path:   markdownPath,
+		letter: letter,
+	})
+}
+
+// SetIndexTitle sets the title of the alphabetical index generated from
+// <!-- index: term --> markers. Defaults to "Index". The index is only
+// rendered when at least one term is marked.
+func (bc *BookCompiler) SetIndexTitle(title string) {
+	bc.indexTitle = title
+}
+
+// SetFrontMatterInToC controls whether front matter sections are listed in
+// the table of contents. Enabled by default.
+func (bc *BookCompiler) SetFrontMatterInToC(include bool) {
+	bc.frontMatterInToC = include
+}
+
 func (bc *BookCompiler) SetToCTitle(title string) {
 	bc.tocTitle = title
 }
 
-// book.go
-func (bc *BookCompiler) cleanText(text string) string {
-	// More robust text cleaning
-	text = strings.ReplaceAll(text, "\n", " ") // Replace newlines with spaces
-	text = strings.ReplaceAll(text, "\t", " ") // Replace tabs with spaces
-	text = strings.TrimSpace(text)             // Remove extra whitespace
+// SetGenerateToC controls whether a table of contents page is produced.
+// Enabled by default. When disabled, the book starts directly with the
+// front matter or first chapter and page numbering starts there. The
+// first layout pass still runs so internal links resolve.
+func (bc *BookCompiler) SetGenerateToC(enable bool) {
+	bc.generateToCPage = enable
+}
 
-	// Remove or replace problematic characters
-	text = strings.ReplaceAll(text, "ðŸ", "")  // Remove emoji placeholders
-	text = strings.ReplaceAll(text, `"`, "\"") // Replace smart quotes
-	text = strings.ReplaceAll(text, `"`, "\"")
-	text = strings.ReplaceAll(text, "'", "'")
-	text = strings.ReplaceAll(text, "'", "'")
-	text = strings.ReplaceAll(text, "…", "...")
-	text = strings.ReplaceAll(text, "–", "-") // Replace en-dash
-	text = strings.ReplaceAll(text, "—", "-") // Replace em-dash
+// SetToCSummaries enables a one-line description beneath each chapter in
+// the table of contents, taken from the "summary" front matter field of
+// the chapter's first file or, failing that, the first sentence of its
+// first paragraph. Disabled by default.
+func (bc *BookCompiler) SetToCSummaries(enable bool) {
+	bc.tocSummaries = enable
+}
+
+// fitText shortens text to fit on one line of the given width in the
+// current font, ending it with "..." when truncated.
+//
+// Parameters:
+//   - text: Text to fit
+//   - width: Available width in millimeters
+//
+// Returns:
+//   - string: The text, possibly truncated
+func (bc *BookCompiler) fitText(text string, width float64) string {
+	if bc.pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	const ellipsis = "..."
+	runes := []rune(text)
+	fits := sort.Search(len(runes), func(i int) bool {
+		return bc.pdf.GetStringWidth(string(runes[:i+1])+ellipsis) > width
+	})
+	return strings.TrimSpace(string(runes[:fits])) + ellipsis
+}
+
+// chapterSummary returns the ToC summary of a chapter.
+//
+// Parameters:
+//   - chapter: Chapter whose summary is needed
+//
+// Returns:
+//   - string: The front matter summary, the first sentence of the first
+//     paragraph, or "" if neither exists
+func (bc *BookCompiler) chapterSummary(chapter Chapter) string {
+	if summary := chapter.Meta[summaryField]; summary != "" {
+		return summary
+	}
+
+	content, err := bc.readFile(chapter.Files[0])
+	if err != nil {
+		return ""
+	}
+	_, content = splitFrontMatter(content)
+
+	var summary string
+	ast := blackfriday.New(blackfriday.WithExtensions(bc.markdownExtensions)).Parse(content)
+	ast.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if entering && node.Type == blackfriday.Paragraph {
+			summary = strings.Join(strings.Fields(getString(node)), " ")
+			return blackfriday.Terminate
+		}
+		return blackfriday.GoToNext
+	})
+
+	if end := strings.Index(summary, ". "); end >= 0 {
+		summary = summary[:end+1]
+	}
+	return summary
+}
+
+// SetToCTitleStyle sets the font family, style, size, and alignment of
+// the table of contents heading, e.g. to match the chapter titles. An
+// empty FontFamily uses the chapter font. Defaults to bold 24pt, left
+// aligned.
+func (bc *BookCompiler) SetToCTitleStyle(style TextStyle) {
+	if style.Size <= 0 {
+		style.Size = chapterTitleSize
+	}
+	bc.tocTitleStyle = style
+}
+
+// SetFigureNumbering selects how figure and table captions are numbered.
+// Numbering is disabled by default.
+func (bc *BookCompiler) SetFigureNumbering(mode FigureNumbering) {
+	bc.figureNumbering = mode
+}
+
+// SetWideTableMode selects how tables too wide for the page are rendered.
+// Defaults to WideTableScaleToFit.
+func (bc *BookCompiler) SetWideTableMode(mode WideTableMode) {
+	bc.wideTableMode = mode
+}
+
+// SetRunningHeaders enables running page headers showing the book title on
+// verso (even) pages and the current chapter title on recto (odd) pages.
+// Headers are suppressed on chapter-opening pages.
+func (bc *BookCompiler) SetRunningHeaders(enable bool) {
+	bc.runningHeaders = enable
+}
+
+// SetContinuationNote appends "(continued)" to the chapter title in running
+// headers on every page of a chapter after its opening page.
+func (bc *BookCompiler) SetContinuationNote(enable bool) {
+	bc.continuationNote = enable
+}
+
+// SetPreserveSingleNewlines renders single newlines inside paragraphs as
+// visible line breaks, regardless of the markdown hard line break setting.
+func (bc *BookCompiler) SetPreserveSingleNewlines(enable bool) {
+	bc.preserveNewlines = enable
+}
+
+// SetGlyphFallbackFont registers a UTF-8 TrueType font used for characters
+// the primary fonts cannot render, such as symbols and non-Latin scripts.
+// Without a fallback font, common symbols (e.g., ✓, →, ★) are drawn from
+// the built-in ZapfDingbats font in body text, titles, ToC entries,
+// captions, and running headers, typographic quotes, dashes, and ellipses
+// are replaced with plain ASCII, and other such characters are dropped
+// from the output with a warning.
+//
+// Parameters:
+//   - family: Family name to register the font under
+//   - fontPath: Path to the .ttf font file
+func (bc *BookCompiler) SetGlyphFallbackFont(family, fontPath string) {
+	bc.glyphFallbackFont = family
+	bc.glyphFallbackPath = fontPath
+}
+
+// SetKerning enables pair kerning (e.g., tightening "AV" or "To"), the
+// only text shaping applied. It affects the title lines drawn on chapter
+// openers and on front matter, back matter, and index pages. Body text,
+// headings within chapters, captions, and tables are not kerned, so that
+// line wrapping stays with gofpdf, and no ligatures are substituted.
+func (bc *BookCompiler) SetKerning(enable bool) {
+	bc.kerning = enable
+}
+
+// SetBookTitle sets the book title used by running headers.
+func (bc *BookCompiler) SetBookTitle(title string) {
+	bc.bookTitle = title
+}
+
+// SetChapterTitleLayout configures chapter opening titles. Section and
+// index titles are not affected.
+//
+// Parameters:
+//   - position: ChapterTitleTop (default), ChapterTitleUpperThird, or
+//     ChapterTitleCentered; the chapter text starts below the title
+//   - rule: Whether a short rule is drawn below the title
+//   - label: Line drawn above the title in a smaller font, with "{n}"
+//     replaced by the chapter number (e.g., "Chapter {n}"); empty for none
+func (bc *BookCompiler) SetChapterTitleLayout(position string, rule bool, label string) {
+	bc.chapterTitlePosition = position
+	bc.chapterTitleRule = rule
+	bc.chapterLabel = label
+}
+
+// SetChapterSubtitles shows the H1 heading a chapter's first markdown file
+// opens with as a subtitle below the chapter title on the opening page
+// (e.g., "Episode 1" over "The Journey Begins"), rather than as a heading
+// on a page of its own. Enabled by default.
+func (bc *BookCompiler) SetChapterSubtitles(enable bool) {
+	bc.chapterSubtitles = enable
+}
+
+// SetHeaderTemplates sets the running header text for verso (even) and
+// recto (odd) pages. Templates may contain the placeholders {book},
+// {chapter}, and {page}. Defaults are "{book}" and "{chapter}".
+func (bc *BookCompiler) SetHeaderTemplates(verso, recto string) {
+	bc.headerVerso = verso
+	bc.headerRecto = recto
+}
+
+// SetHeaderRule configures the rule drawn beneath the page header.
+//
+// Parameters:
+//   - enabled: Whether the rule is drawn
+//   - weight: Line width in millimeters
+//   - r, g, b: Line color components (0-255)
+func (bc *BookCompiler) SetHeaderRule(enabled bool, weight float64, r, g, b int) {
+	bc.headerRule = enabled
+	bc.headerRuleWeight = weight
+	bc.headerRuleColor = rgbColor{r, g, b}
+}
+
+// SetWatermark stamps rotated, semi-transparent text diagonally across
+// every page (e.g., "DRAFT"). An empty text disables the text watermark.
+//
+// Parameters:
+//   - text: Watermark text
+//   - opacity: Alpha from 0 (invisible) to 1 (opaque)
+func (bc *BookCompiler) SetWatermark(text string, opacity float64) {
+	bc.watermarkText = text
+	bc.watermarkOpacity = opacity
+}
 
+// SetWatermarkImage stamps the image at path, centered, on every page using
+// the watermark opacity. An empty path disables the image watermark.
+func (bc *BookCompiler) SetWatermarkImage(path string) {
+	bc.watermarkImage = path
+}
+
+// SetListMarkerColor sets the color of list bullets and numbers, leaving
+// the item text in the current text color.
+func (bc *BookCompiler) SetListMarkerColor(r, g, b int) {
+	bc.listMarkerColor = &rgbColor{r, g, b}
+}
+
+// SetTextColor sets the body text color. Defaults to black.
+func (bc *BookCompiler) SetTextColor(r, g, b int) {
+	bc.textColor = rgbColor{r, g, b}
+}
+
+// SetHeadingColor sets the color of headings and chapter titles.
+// Defaults to black.
+func (bc *BookCompiler) SetHeadingColor(r, g, b int) {
+	bc.headingColor = rgbColor{r, g, b}
+}
+
+// SetBackgroundColor fills every page with the given color, e.g. for
+// dark-themed books. Pages are white by default.
+func (bc *BookCompiler) SetBackgroundColor(r, g, b int) {
+	bc.backgroundColor = &rgbColor{r, g, b}
+}
+
+// SetLineSpacing sets the leading of body text as a multiple of the base
+// line height; 2.0 produces a double-spaced manuscript. Paragraphs,
+// blockquotes, code blocks, list items, and captions are affected. Tables,
+// the spacing around headings, the table of contents, and the index stay
+// fixed so their layout remains compact. Non-positive factors are ignored.
+//
+// Parameters:
+//   - factor: Line height multiplier (1.0 = single spacing)
+func (bc *BookCompiler) SetLineSpacing(factor float64) {
+	if factor > 0 {
+		bc.lineSpacing = factor
+	}
+}
+
+// SetParagraphStyle sets the first-line indent and the gap between
+// paragraphs. Use an indent with little or no spacing for the classic book
+// look, or spacing without an indent for the web look. The first paragraph
+// after a heading is never indented. Negative values are treated as zero.
+//
+// Parameters:
+//   - indent: First-line indent in millimeters
+//   - spacing: Vertical space before each paragraph in millimeters
+func (bc *BookCompiler) SetParagraphStyle(indent, spacing float64) {
+	bc.paragraphIndent = math.Max(indent, 0)
+	bc.paragraphSpacing = math.Max(spacing, 0)
+}
+
+// SetMarkdownExtensions selects the blackfriday extensions used when
+// parsing markdown, for example
+// blackfriday.Tables|blackfriday.Footnotes|blackfriday.AutoHeadingIDs.
+// Defaults to blackfriday.CommonExtensions plus AutoHeadingIDs. Heading IDs
+// (HeadingIDs or AutoHeadingIDs) are needed for links to sections and a
+// clickable table of contents.
+//
+// Parameters:
+//   - extensions: Extension flags passed to the markdown parser
+func (bc *BookCompiler) SetMarkdownExtensions(extensions blackfriday.Extensions) {
+	bc.markdownExtensions = extensions
+}
+
+// SetSmartPunctuation converts straight quotes to curly ones, "--" and
+// "---" to en and em dashes, and 1/2, 1/4, and 3/4 to their single
+// characters, as blackfriday's smartypants does. It is disabled by
+// default: the core fonts lack these characters, so without a glyph
+// fallback font they are replaced with ASCII again, losing the
+// distinction between "--" and "-".
+func (bc *BookCompiler) SetSmartPunctuation(enable bool) {
+	bc.smartPunctuation = enable
+}
+
+// SetHyphenation breaks words that do not fit at the end of a line at
+// hyphenation points found with Liang's algorithm, in body text and in
+// SplitText. lang selects the patterns: "en" (also "en-us" and "en-gb")
+// uses a compact embedded English set, and other languages need
+// LoadHyphenationPatterns. An empty lang, the default, disables
+// hyphenation.
+func (bc *BookCompiler) SetHyphenation(lang string) {
+	bc.hyphenation = strings.ToLower(lang)
+}
+
+// SetHeadingStyle sets the font and spacing used for one heading level.
+// An empty FontFamily uses the chapter font; an Alignment of AlignCenter or
+// AlignRight centers or right-aligns headings that fit on one line.
+//
+// Parameters:
+//   - level: Heading level, 1 for h1 through 6 for h6
+//   - style: Font family, style ("", "B", "I", "BI"), and size in points
+//   - spaceBefore: Vertical space above the heading in millimeters
+//   - spaceAfter: Vertical space below the heading in millimeters
+func (bc *BookCompiler) SetHeadingStyle(level int, style TextStyle, spaceBefore, spaceAfter float64) {
+	if level < 1 || level > 6 || style.Size <= 0 {
+		return
+	}
+	bc.headingStyles[level] = headingStyle{
+		text:        style,
+		spaceBefore: spaceBefore,
+		spaceAfter:  spaceAfter,
+	}
+}
+
+// SetPageBreakBeforeH1 controls whether every h1 starts a new page.
+// Enabled by default; disable it when h1 marks ordinary sections that
+// should flow on the same page.
+func (bc *BookCompiler) SetPageBreakBeforeH1(enable bool) {
+	bc.SetPageBreakBefore(1, enable)
+}
+
+// SetPageBreakBefore controls whether headings of the given level (1-6)
+// start a new page. See also SetPageBreakHeadingLevel.
+//
+// Parameters:
+//   - level: Heading level, 1 for h1 through 6 for h6
+//   - enable: true to start each such heading on a new page
+func (bc *BookCompiler) SetPageBreakBefore(level int, enable bool) {
+	bc.pageBreakBefore[level] = enable
+}
+
+// SetPageBreakHeadingLevel makes headings at or above the given level
+// start a new page and lets deeper headings flow; for example, 2 starts
+// every h1 and h2 on a fresh page. Zero disables forced heading breaks.
+//
+// Parameters:
+//   - maxLevel: Deepest heading level (0-6) that forces a page break
+func (bc *BookCompiler) SetPageBreakHeadingLevel(maxLevel int) {
+	for level := 1; level <= 6; level++ {
+		bc.pageBreakBefore[level] = level <= maxLevel
+	}
+}
+
+// SetKeepWithNext sets how many lines of body text must fit below a
+// heading on the same page. Headings that would be followed by fewer lines
+// are moved to the next page. Zero only requires the heading itself to fit.
+//
+// Parameters:
+//   - lines: Number of following lines to keep with each heading
+func (bc *BookCompiler) SetKeepWithNext(lines int) {
+	if lines < 0 {
+		lines = 0
+	}
+	bc.keepWithNextLines = lines
+}
+
+// SetWidowOrphanControl enables or disables widow and orphan control for
+// paragraphs. Enabled by default.
+func (bc *BookCompiler) SetWidowOrphanControl(enable bool) {
+	bc.widowOrphanControl = enable
+}
+
+// SetPageBreakThreshold sets the space, in millimeters above the bottom
+// margin, that a paragraph, blockquote, or code block needs to start on
+// the current page; with less, it starts on the next page. Zero, the
+// default, derives the threshold from the body text: the space above a
+// block plus one line. Negative values are treated as zero.
+func (bc *BookCompiler) SetPageBreakThreshold(mm float64) {
+	bc.pageBreakThreshold = max(mm, 0)
+}
+
+// SetLinkStyle sets the color of external links and whether they are
+// underlined. Defaults to blue (0, 0, 255) without underline.
+func (bc *BookCompiler) SetLinkStyle(r, g, b int, underline bool) {
+	bc.linkColor = rgbColor{r, g, b}
+	bc.linkUnderline = underline
+}
+
+// SetAllowRemoteImages enables downloading of images referenced by http or
+// https URLs. Disabled by default; downloads are subject to a timeout and
+// size limit and are removed when compilation finishes.
+func (bc *BookCompiler) SetAllowRemoteImages(allow bool) {
+	bc.allowRemoteImages = allow
+}
+
+// AddSource adds a directory whose episode chapters are merged with those
+// in the root directory. All chapters are ordered by episode number; an
+// episode number that appears in more than one source directory makes
+// compilation fail with ErrDuplicateEpisode.
+//
+// Parameters:
+//   - dir: Directory containing EpisodeNN chapter subdirectories
+func (bc *BookCompiler) AddSource(dir string) {
+	bc.sources = append(bc.sources, dir)
+}
+
+// SetChapterRange limits compilation to the episodes numbered from through
+// to, inclusive, to speed up work on part of a large book. The table of
+// contents and page numbers cover only those chapters; front and back
+// matter are still included. A bound of zero leaves that end of the range
+// open, and SetChapterRange(0, 0) compiles every chapter again.
+//
+// Parameters:
+//   - from: First episode number to include
+//   - to: Last episode number to include
+func (bc *BookCompiler) SetChapterRange(from, to int) {
+	bc.chapterRangeFrom = from
+	bc.chapterRangeTo = to
+}
+
+// SetIgnorePatterns sets gitignore-style patterns of files and
+// directories to leave out of the book, such as "README.md", "notes/", or
+// "*.wip.md". Patterns match paths relative to RootDir and to each
+// directory added with AddSource; a ".bookieignore" file in those
+// directories adds patterns of its own, one per line, after these.
+//
+// Parameters:
+//   - patterns: Patterns in the order they apply; "!" before a pattern
+//     includes again paths excluded by earlier ones
+func (bc *BookCompiler) SetIgnorePatterns(patterns []string) {
+	bc.ignorePatterns = append([]string(nil), patterns...)
+}
+
+// SetDropCaps enables a large decorative initial, spanning three lines,
+// on the first paragraph after each chapter title. No drop cap is drawn
+// when the chapter opens with a heading or with punctuation.
+func (bc *BookCompiler) SetDropCaps(enable bool) {
+	bc.dropCaps = enable
+}
+
+// SetBlockquoteBar sets the width in millimeters and color of the vertical
+// accent bar drawn beside blockquotes. A width of zero disables the bar.
+func (bc *BookCompiler) SetBlockquoteBar(width float64, r, g, b int) {
+	bc.blockquoteBarWidth = width
+	bc.blockquoteBarColor = rgbColor{r, g, b}
+}
+
+// SetBlockquoteBackground sets a light background tint spanning the full
+// height of each blockquote. No tint is drawn by default.
+func (bc *BookCompiler) SetBlockquoteBackground(r, g, b int) {
+	bc.blockquoteBackground = &rgbColor{r, g, b}
+}
+
+// SetHighlightColor sets the background color of highlighted (<mark>)
+// text. Defaults to yellow.
+func (bc *BookCompiler) SetHighlightColor(r, g, b int) {
+	bc.highlightColor = rgbColor{r, g, b}
+}
+
+// SetTableFont sets the font family and size of table text, independent
+// of body text, e.g. a condensed font for data-heavy tables. Row heights
+// scale with the size. Pass an empty family to keep the body text font.
+// Defaults to the body font at 10pt; a size of zero or less keeps 10pt.
+func (bc *BookCompiler) SetTableFont(family string, size float64) {
+	if size <= 0 {
+		size = tableFontSize
+	}
+	bc.tableFont = family
+	bc.tableTextSize = size
+}
+
+// SetTableBorderStyle selects which table borders are drawn:
+// TableBorderGrid (default) boxes every cell, TableBorderHorizontal draws
+// rules between rows only, and TableBorderNone draws no borders. Header
+// and striped row backgrounds are filled in every style.
+func (bc *BookCompiler) SetTableBorderStyle(style string) {
+	bc.tableBorderStyle = style
+}
+
+// SetTableZebra enables alternating row shading in tables: every second
+// data row is filled with the stripe color (see SetTableZebraColor).
+// Disabled by default.
+func (bc *BookCompiler) SetTableZebra(enable bool) {
+	bc.tableZebra = enable
+}
+
+// SetTableZebraColor sets the background color of striped table rows.
+// Defaults to a light gray.
+func (bc *BookCompiler) SetTableZebraColor(r, g, b int) {
+	bc.tableZebraColor = rgbColor{r, g, b}
+}
+
+// SetThematicBreakStyle selects how horizontal rules ("---") render:
+// ThematicBreakRule (default) draws a line, ThematicBreakAsterisks a
+// centered "* * *" scene break for fiction, and ThematicBreakSpace only
+// blank space.
+func (bc *BookCompiler) SetThematicBreakStyle(style string) {
+	bc.thematicBreakStyle = style
+}
+
+// SetIntraChapterSeparator selects what is placed between consecutive
+// markdown files of a chapter: FileSeparatorSpace (default, two blank
+// lines), FileSeparatorRule, FileSeparatorPageBreak, or FileSeparatorNone.
+func (bc *BookCompiler) SetIntraChapterSeparator(separator string) {
+	bc.fileSeparator = separator
+}
+
+// SetTextDirection selects TextDirectionLTR (default) or
+// TextDirectionRTL. Right to left, paragraphs and headings are wrapped and
+// right-aligned, list markers are placed at the right, and list and
+// blockquote indentation is taken from the right margin. Use it with a
+// glyph fallback font covering the script, as the core fonts have no
+// Arabic or Hebrew letters. Inline formatting and links within
+// right-to-left paragraphs, headings, and list items are not rendered,
+// and Arabic letters are not shaped; code blocks and tables stay left to
+// right.
+func (bc *BookCompiler) SetTextDirection(direction string) {
+	bc.textDirection = direction
+}
+
+// SetProgressFunc sets a callback invoked after each chapter file and
+// front or back matter section is rendered, for progress bars in UIs and
+// command-line tools. The callback does not affect the generated PDF.
+// Pass nil to disable progress reporting.
+func (bc *BookCompiler) SetProgressFunc(fn ProgressFunc) {
+	bc.progress = fn
+}
+
+// SetProcessingStats enables collection of per-phase timings, which are
+// available from Timings after Compile returns.
+func (bc *BookCompiler) SetProcessingStats(enable bool) {
+	bc.collectStats = enable
+}
+
+// Timings returns the phase timings recorded during the last compilation.
+// All durations are zero unless SetProcessingStats(true) was called.
+func (bc *BookCompiler) Timings() PhaseTimings {
+	return bc.timings
+}
+
+// trackPhase adds the time elapsed since start to the given phase timing
+// when processing stats are enabled. Intended for use with defer.
+func (bc *BookCompiler) trackPhase(phase *time.Duration, start time.Time) {
+	if bc.collectStats {
+		*phase += time.Since(start)
+	}
+}
+
+// SetTaggedPDF enables accessibility markup in the generated PDF.
+// Headings are wrapped in marked-content sequences tagged H1-H6, images
+// in Figure sequences carrying their alt text as the alternate
+// description, and the document language is recorded in the XMP metadata.
+//
+// gofpdf cannot write catalog entries, so the PDF has no structure tree,
+// /MarkInfo, or /Lang entry and is not a conforming tagged PDF (PDF/UA).
+// Paragraphs, lists, and tables are not marked, and marked content that
+// breaks across pages only covers its first page.
+func (bc *BookCompiler) SetTaggedPDF(enable bool) {
+	bc.taggedPDF = enable
+}
+
+// SetImageDownsampleDPI reduces the resolution of JPEG images whose
+// pixel density at their display size exceeds dpi, re-encoding them before
+// they are embedded. This greatly shrinks photo-heavy books; 150 suits
+// screen reading and 300 print. Pass 0 (the default) to embed images
+// unchanged.
+func (bc *BookCompiler) SetImageDownsampleDPI(dpi int) {
+	bc.imageDPI = dpi
+}
+
+// SetMath enables math: inline math between single dollar signs
+// ($E = mc^2$) and display math between double ones ($$...$$) is
+// rendered instead of printed literally. Without a math renderer, simple
+// expressions (symbols, superscripts, subscripts, \frac, \sqrt) are
+// drawn as text; Greek letters and most operators need a glyph fallback
+// font. Write "\$" for a literal dollar sign while math is enabled.
+func (bc *BookCompiler) SetMath(enable bool) {
+	bc.math = enable
+}
+
+// SetMathRenderer sets an external converter, such as a LaTeX toolchain,
+// that renders math to JPEG images, and enables math. Display math
+// images are embedded like other images; inline ones are scaled to the
+// line height. Each distinct expression is converted once per compiler.
+// Pass nil to return to the built-in renderer.
+func (bc *BookCompiler) SetMathRenderer(fn MathRenderer) {
+	bc.mathRenderer = fn
+	if fn != nil {
+		bc.math = true
+	}
+}
+
+// SetFenceRenderer registers a converter for fenced code blocks tagged
+// with a language, such as "mermaid" or "plantuml". Such blocks are passed
+// to fn and the JPEG image it returns is embedded in place of the code;
+// each distinct block is converted once per compiler. Blocks in other
+// languages render as code. Pass a nil fn to remove the converter.
+func (bc *BookCompiler) SetFenceRenderer(lang string, fn FenceRenderer) {
+	if fn == nil {
+		delete(bc.fenceRenderers, lang)
+		return
+	}
+	if bc.fenceRenderers == nil {
+		bc.fenceRenderers = make(map[string]FenceRenderer)
+	}
+	bc.fenceRenderers[lang] = fn
+}
+
+// SetSVGRasterizer sets the converter used to embed SVG images, which
+// gofpdf cannot draw: each SVG document is passed to fn, once per
+// compiler, and the JPEG image it returns is embedded in its place.
+// Without a rasterizer, an SVG image fails with ErrNoSVGRasterizer.
+func (bc *BookCompiler) SetSVGRasterizer(fn SVGRasterizer) {
+	bc.svgRasterizer = fn
+}
+
+// SetProtection encrypts the output PDF. Readers must enter userPassword
+// to open it (none if empty) and are limited to the given permissions, a
+// combination of PermitPrint, PermitModify, PermitCopy, and PermitAnnotate;
+// ownerPassword lifts the limits. An empty ownerPassword is replaced by a
+// random one, which makes output nondeterministic.
+//
+// gofpdf uses 40-bit RC4 encryption, which is easily broken, and
+// permissions are only honored by cooperating viewers. Treat protection
+// as a deterrent, not as security for sensitive content.
+func (bc *BookCompiler) SetProtection(permissions byte, userPassword, ownerPassword string) {
+	bc.protection = &protection{permissions, userPassword, ownerPassword}
+}
+
+// SetCompression enables or disables zlib compression of page content.
+// Compression is on by default and roughly halves the size of text-heavy
+// books at a small cost in compile time. Disable it to inspect the PDF
+// source while debugging; images are embedded in their own encoding
+// either way, so image-heavy books change little.
+func (bc *BookCompiler) SetCompression(enable bool) {
+	bc.compression = enable
+}
+
+// SetDeterministic enables reproducible output: the PDF creation and
+// modification dates are fixed and internal resources are written in a
+// consistent order, so compiling identical input yields identical bytes
+// for build caching, hashing, and diffing.
+func (bc *BookCompiler) SetDeterministic(enable bool) {
+	bc.deterministic = enable
+}
+
+// SetLanguage sets the document language as a BCP 47 tag (e.g., "en-US"),
+// which screen readers use to choose pronunciation. gofpdf cannot write
+// the catalog /Lang entry, so the language is recorded as dc:language in
+// the PDF's XMP metadata.
+func (bc *BookCompiler) SetLanguage(lang string) {
+	bc.language = lang
+}
+
+// SetPageLayout sets how viewers arrange pages when the PDF is opened:
+// PageLayoutSingle, PageLayoutContinuous, PageLayoutTwoColumn, or
+// PageLayoutTwoPage for a print-like spread. Unknown layouts cause
+// Compile to fail. Empty leaves the choice to the viewer.
+//
+// The viewer's page mode is not configurable: gofpdf opens the bookmarks
+// panel, as every book has bookmarks for its chapters.
+func (bc *BookCompiler) SetPageLayout(layout string) {
+	bc.pageLayout = layout
+}
+
+// SetInitialZoom sets the zoom level viewers use when the PDF is opened:
+// ZoomFullPage, ZoomFullWidth, or ZoomActualSize. Unknown values cause
+// Compile to fail. Empty leaves the choice to the viewer.
+func (bc *BookCompiler) SetInitialZoom(zoom string) {
+	bc.initialZoom = zoom
+}
+
+// SetAltTextWarnings enables a logged warning for each image rendered
+// without alt text, to help produce accessible PDFs. Such images are
+// always listed by MissingAltText.
+func (bc *BookCompiler) SetAltTextWarnings(enable bool) {
+	bc.altTextWarnings = enable
+}
+
+// MissingAltText returns the images rendered without alt text during the
+// last compilation, each as "file: src".
+func (bc *BookCompiler) MissingAltText() []string {
+	return append([]string(nil), bc.missingAltText...)
+}
+
+// SetContinueOnError keeps compiling when an image, including a chapter
+// cover, cannot be found or loaded: a placeholder box naming the image is
+// drawn in its place, a warning is logged, and once the output is written
+// Compile or CompileSplit returns an error wrapping ErrUnresolvedImages
+// that lists every such image. Other errors still stop compilation.
+// Disabled by default.
+func (bc *BookCompiler) SetContinueOnError(enable bool) {
+	bc.continueOnError = enable
+}
+
+// UnresolvedImages returns the images replaced by placeholders during the
+// last compilation, each as "file: src".
+func (bc *BookCompiler) UnresolvedImages() []string {
+	return append([]string(nil), bc.unresolvedImages...)
+}
+
+// CaptionLabel returns the numbered label assigned to the figure or table
+// with the given element id during the last compilation (e.g., "Figure 2").
+// The second return value is false if no such label exists.
+func (bc *BookCompiler) CaptionLabel(id string) (string, bool) {
+	label, ok := bc.captionLabels[id]
+	return label, ok
+}
+
+// cleanText prepares text for the core PDF fonts: whitespace runs are
+// collapsed to a single space and characters without a core font glyph
+// are dropped. Single spaces at the start or end are kept, since they
+// separate the text from neighbouring inline elements.
+//
+// Parameters:
+//   - text: Raw text content
+//
+// Returns:
+//   - string: Text safe for output with the core fonts
+func (bc *BookCompiler) cleanText(text string) string {
 	// Remove any other non-printable characters
 	clean := strings.Map(func(r rune) rune {
-		if r < 32 || r >= 127 {
+		if !hasCoreGlyph(r) {
+			// Runes body text can render are not reported
+			if _, _, ok := bc.glyphFor(r); !ok {
+				bc.warnUnsupportedGlyph(r)
+			}
 			return -1
 		}
 		return r
-	}, text)
+	}, asciiTypography.Replace(bc.normalizeText(text)))
 
 	return clean
 }
+
+// isCoreText reports whether the core fonts can render all of a text
+// without a fallback font.
+func isCoreText(text string) bool {
+	return strings.IndexFunc(text, func(r rune) bool { return !hasCoreGlyph(r) }) < 0
+}
+
+// normalizeText collapses whitespace and, unless a glyph fallback font
+// can render them, replaces typographic characters with their plain
+// equivalents, leaving other characters untouched.
+func (bc *BookCompiler) normalizeText(text string) string {
+	// Collapse runs of spaces, tabs, and newlines, keeping boundary spaces
+	text = whitespaceRun.ReplaceAllString(text, " ")
+
+	// Remove or replace problematic characters
+	text = strings.ReplaceAll(text, "ðŸ", "") // Remove emoji placeholders
+	if bc.glyphFallbackFont == "" {
+		text = asciiTypography.Replace(text)
+	}
+
+	return text
+}
+
+// hasCoreGlyph reports whether a rune can be rendered by the built-in
+// core fonts without translation.
+func hasCoreGlyph(r rune) bool {
+	return r >= 32 && r < 127
+}
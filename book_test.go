@@ -0,0 +1,116 @@
+package bookie
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestFrontMatterIndexTermsOutsideToC(t *testing.T) {
+	root := writeBook(t, map[string]string{
+		"preface.md":           "A word about <!-- index: gardens --> gardens.\n\n## Preface Heading\n",
+		"Episode01/content.md": "Chapter text.\n",
+	})
+	bc, pdf := compileRecorded(t, root, func(bc *BookCompiler) {
+		bc.AddFrontMatter("Preface", filepath.Join(root, "preface.md"))
+		bc.SetFrontMatterInToC(false)
+	})
+
+	var indexEntry *ToCEntry
+	for i, entry := range bc.toc {
+		switch entry.Title {
+		case "Preface", "Preface Heading":
+			t.Errorf("ToC lists front matter entry %q", entry.Title)
+		case defaultIndexTitle:
+			indexEntry = &bc.toc[i]
+		}
+	}
+	if indexEntry == nil {
+		t.Fatal("ToC has no index entry for a term marked in front matter")
+	}
+	text := pdf.pageText(indexEntry.PageNum)
+	if !strings.Contains(text, defaultIndexTitle) || !strings.Contains(text, "gardens") {
+		t.Errorf("page %d listed in the ToC for the index has %q", indexEntry.PageNum, text)
+	}
+}
+
+func TestProcessingStatsRecordsPhases(t *testing.T) {
+	root := writeBook(t, map[string]string{
+		"Episode01/content.md": "# Heading\n\nSome *text* to render.\n",
+	})
+	bc, _ := compileRecorded(t, root, func(bc *BookCompiler) {
+		bc.SetProcessingStats(true)
+	})
+
+	timings := bc.Timings()
+	if timings.Rendering <= 0 {
+		t.Errorf("Rendering = %v, want a positive duration", timings.Rendering)
+	}
+	for name, phase := range map[string]time.Duration{
+		"Discovery":  timings.Discovery,
+		"Conversion": timings.Conversion,
+		"Parsing":    timings.Parsing,
+		"Output":     timings.Output,
+	} {
+		if phase <= 0 {
+			t.Errorf("%s = %v, want it recorded", name, phase)
+		}
+	}
+
+	bc, _ = compileRecorded(t, root, nil)
+	if timings := bc.Timings(); timings != (PhaseTimings{}) {
+		t.Errorf("Timings = %+v without processing stats, want zero", timings)
+	}
+}
+
+func TestFitTextKeepsWholeRunes(t *testing.T) {
+	bc := newTestCompiler(t)
+	text := strings.Repeat("Ωé", 40)
+	for width := 5.0; width <= 60; width += 0.5 {
+		got := bc.fitText(text, width)
+		if !utf8.ValidString(got) {
+			t.Errorf("width %.1f: fitText returned invalid UTF-8 %q", width, got)
+		}
+		if !strings.HasSuffix(got, "...") || !strings.HasPrefix(text, strings.TrimSuffix(got, "...")) {
+			t.Errorf("width %.1f: fitText = %q, want a prefix of the text and an ellipsis", width, got)
+		}
+		if w := bc.pdf.GetStringWidth(got); w > width {
+			t.Errorf("width %.1f: fitted text is %.1f wide", width, w)
+		}
+	}
+}
+
+func TestAppendixHeadingsNumberedAndBookmarked(t *testing.T) {
+	root := writeBook(t, map[string]string{
+		"Episode01/content.md": "Chapter text.\n\n## Background\n",
+		"appendix.md":          "## Setup\n\n### Linux\n\nText.\n\n## Usage\n\nText.\n",
+	})
+	bc, pdf := compileRecorded(t, root, func(bc *BookCompiler) {
+		bc.AddAppendix("Tools", filepath.Join(root, "appendix.md"))
+	})
+
+	var toc []string
+	for _, entry := range bc.toc {
+		toc = append(toc, entry.Title)
+	}
+	wantToC := []string{"Episode01", "Background", "Appendix A: Tools", "A.1 Setup", "A.1.1 Linux", "A.2 Usage"}
+	if strings.Join(toc, "|") != strings.Join(wantToC, "|") {
+		t.Errorf("ToC entries = %q, want %q", toc, wantToC)
+	}
+
+	var bookmarks []string
+	for _, call := range pdf.methodCalls("Bookmark") {
+		bookmarks = append(bookmarks, fmt.Sprintf("%d %s", call.level, call.text))
+	}
+	wantBookmarks := []string{"0 Episode 01", "1 Background", "0 Appendix A: Tools", "1 A.1 Setup", "2 A.1.1 Linux", "1 A.2 Usage"}
+	if strings.Join(bookmarks, "|") != strings.Join(wantBookmarks, "|") {
+		t.Errorf("bookmarks = %q, want %q", bookmarks, wantBookmarks)
+	}
+
+	if page := pdf.pageOf("A.1.1 "); page == 0 || page != pdf.pageOf("Linux") {
+		t.Errorf("heading number drawn on page %d, heading on page %d", page, pdf.pageOf("Linux"))
+	}
+}
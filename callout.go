@@ -0,0 +1,307 @@
+package bookie
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Callout box layout, in millimeters.
+const (
+	calloutPadding     = 4.0 // Space between the box border and its content
+	calloutBorderWidth = 0.4 // Width of the box border
+	calloutTintRatio   = 0.1 // Strength of the background tint relative to the border color
+)
+
+// calloutKind describes one kind of callout box.
+type calloutKind struct {
+	// title is shown next to the icon at the top of the box
+	title string
+
+	// icon is the symbolFont character drawn before the title
+	icon byte
+
+	// color is used for the border, icon, and title; the background is a
+	// light tint of it
+	color rgbColor
+}
+
+// calloutKinds maps div and span classes, and admonition types, to the
+// callout they render as.
+var calloutKinds = map[string]calloutKind{
+	"note":      {title: "Note", icon: 0x2E, color: rgbColor{9, 105, 218}},       // pencil
+	"tip":       {title: "Tip", icon: 0x48, color: rgbColor{26, 127, 55}},        // star
+	"important": {title: "Important", icon: 0x75, color: rgbColor{130, 80, 223}}, // diamond
+	"warning":   {title: "Warning", icon: 0xA2, color: rgbColor{154, 103, 0}},    // exclamation mark
+	"caution":   {title: "Caution", icon: 0x36, color: rgbColor{207, 34, 46}},    // cross
+}
+
+// admonitionFence matches a line opening a fenced admonition (":::note",
+// optionally followed by a title) or closing one (":::").
+var admonitionFence = regexp.MustCompile(`^:::\s*([A-Za-z]*)\s*(.*?)\s*$`)
+
+// admonitionComment matches the comment an opening fence is rewritten to.
+var admonitionComment = regexp.MustCompile(`^\s*admonition:([a-z]+)\s*(.*?)\s*$`)
+
+// admonitionEnd is the comment a closing fence is rewritten to.
+const admonitionEnd = "/admonition"
+
+// codeFence matches a line opening or closing a fenced code block.
+var codeFence = regexp.MustCompile("^ {0,3}(```|~~~)")
+
+// admonitionMarker matches the marker starting a GitHub-style alert
+// blockquote, e.g. "[!NOTE]".
+var admonitionMarker = regexp.MustCompile(`^\s*\[!([A-Za-z]+)\]\s*`)
+
+// elementCallout returns the callout kind named by an element's classes.
+//
+// Parameters:
+//   - n: Element to check
+//
+// Returns:
+//   - calloutKind: The callout kind of the first recognized class
+//   - bool: false if none of the element's classes is a callout kind
+func elementCallout(n *html.Node) (calloutKind, bool) {
+	for _, class := range strings.Fields(getAttr(n, "class")) {
+		if kind, ok := calloutKinds[class]; ok {
+			return kind, true
+		}
+	}
+	return calloutKind{}, false
+}
+
+// renderCallout renders content in a tinted, bordered box headed by the
+// callout's icon and title. The box is drawn on every page the content
+// spans.
+//
+// Parameters:
+//   - n: Element whose children form the box content
+//   - kind: Callout kind giving the title, icon, and color
+//
+// Returns:
+//   - error: Any rendering errors encountered
+func (bc *BookCompiler) renderCallout(n *html.Node, kind calloutKind) error {
+	left, _, right, _ := bc.pdf.GetMargins()
+	bc.pdf.Ln(defaultLineHeight)
+	startPage, startY := bc.pdf.PageNo(), bc.pdf.GetY()
+
+	bc.pdf.SetLeftMargin(left + calloutPadding)
+	bc.pdf.SetRightMargin(right + calloutPadding)
+	bc.pdf.SetY(startY + calloutPadding)
+
+	previousColor := bc.setTextColor(kind.color)
+	bc.setFont(symbolFont, fontStyleNormal, defaultFontSize)
+	bc.pdf.Write(bc.lineHeight(), string([]byte{kind.icon})+" ")
+	bc.setFont(bc.chapterFont, fontStyleBold, defaultFontSize)
+	bc.writeWithFallback(bc.normalizeText(kind.title))
+	bc.restoreTextColor(previousColor)
+	bc.pdf.Ln(bc.lineHeight())
+
+	bc.setFont(bc.textFont, fontStyleNormal, defaultFontSize)
+	err := bc.renderChildren(n)
+	bc.pdf.SetLeftMargin(left)
+	bc.pdf.SetRightMargin(right)
+	bc.pdf.SetY(bc.pdf.GetY() + calloutPadding)
+
+	bc.drawCalloutBox(startPage, startY, kind.color)
+	bc.pdf.Ln(defaultLineHeight)
+	return err
+}
+
+// drawCalloutBox draws the background and border of a rendered callout
+// on every page it spans, ending at the current position. The background
+// is blended with multiply so the text drawn earlier stays visible.
+//
+// Parameters:
+//   - startPage: Page on which the callout begins
+//   - startY: Top of the callout on its first page
+//   - color: Border color
+func (bc *BookCompiler) drawCalloutBox(startPage int, startY float64, color rgbColor) {
+	endPage, endX, endY := bc.pdf.PageNo(), bc.pdf.GetX(), bc.pdf.GetY()
+	width, height := bc.pdf.GetPageSize()
+	left, top, right, _ := bc.pdf.GetMargins()
+	_, bottom := bc.pdf.GetAutoPageBreak()
+	tint := func(c int) int { return 255 - int(float64(255-c)*calloutTintRatio) }
+
+	drawR, drawG, drawB := bc.pdf.GetDrawColor()
+	lineWidth := bc.pdf.GetLineWidth()
+	bc.pdf.SetDrawColor(color.r, color.g, color.b)
+	bc.pdf.SetLineWidth(calloutBorderWidth)
+
+	for page := startPage; page <= endPage; page++ {
+		bc.pdf.SetPage(page)
+		y0, y1 := top, height-bottom
+		if page == startPage {
+			y0 = startY
+		}
+		if page == endPage {
+			y1 = endY
+		}
+		if y1 <= y0 {
+			continue
+		}
+
+		bc.pdf.SetAlpha(1, "Multiply")
+		bc.pdf.SetFillColor(tint(color.r), tint(color.g), tint(color.b))
+		bc.pdf.Rect(left, y0, width-right-left, y1-y0, "F")
+		bc.pdf.SetAlpha(1, "Normal")
+		bc.pdf.Rect(left, y0, width-right-left, y1-y0, "D")
+	}
+
+	bc.pdf.SetDrawColor(drawR, drawG, drawB)
+	bc.pdf.SetLineWidth(lineWidth)
+	bc.pdf.SetPage(endPage)
+	bc.pdf.SetXY(endX, endY)
+}
+
+// rewriteAdmonitionFences replaces admonition fence lines outside code
+// blocks with HTML comments, which blackfriday passes through as separate
+// blocks, for groupAdmonitions to find. Left as text, a fence would merge
+// into the neighboring paragraph or start a definition list.
+//
+// Parameters:
+//   - content: Markdown content
+//
+// Returns:
+//   - []byte: The content with fence lines rewritten
+func rewriteAdmonitionFences(content []byte) []byte {
+	if !bytes.Contains(content, []byte(":::")) {
+		return content
+	}
+
+	var out bytes.Buffer
+	inCode := false
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		text := strings.TrimRight(string(line), "\r\n")
+		if codeFence.MatchString(text) {
+			inCode = !inCode
+		}
+		match := admonitionFence.FindStringSubmatch(text)
+		if inCode || match == nil {
+			out.Write(line)
+			continue
+		}
+
+		kind := strings.ToLower(match[1])
+		if _, ok := calloutKinds[kind]; ok {
+			title := strings.ReplaceAll(match[2], "--", "")
+			fmt.Fprintf(&out, "\n<!-- admonition:%s %s -->\n\n", kind, title)
+		} else if kind == "" {
+			fmt.Fprintf(&out, "\n<!-- %s -->\n\n", admonitionEnd)
+		} else {
+			out.Write(line)
+		}
+	}
+	return out.Bytes()
+}
+
+// groupAdmonitions moves the nodes between admonition fence comments
+// into a div with the admonition's class, and a title attribute if the
+// opening fence names one, so they render as a callout. Fences may nest;
+// an unclosed fence extends to the end of its parent.
+//
+// Parameters:
+//   - n: Element whose children are grouped, recursively
+func groupAdmonitions(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		kind, title, ok := admonitionOpening(c)
+		if !ok {
+			if c.Type == html.ElementNode {
+				groupAdmonitions(c)
+			}
+			continue
+		}
+
+		div := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+		div.Attr = append(div.Attr, html.Attribute{Key: "class", Val: kind})
+		if title != "" {
+			div.Attr = append(div.Attr, html.Attribute{Key: "title", Val: title})
+		}
+		n.InsertBefore(div, c)
+		n.RemoveChild(c)
+
+		depth := 0
+		for next := div.NextSibling; next != nil; next = div.NextSibling {
+			if _, _, ok := admonitionOpening(next); ok {
+				depth++
+			} else if isAdmonitionClosing(next) {
+				if depth == 0 {
+					n.RemoveChild(next)
+					break
+				}
+				depth--
+			}
+			n.RemoveChild(next)
+			div.AppendChild(next)
+		}
+		groupAdmonitions(div)
+		c = div
+	}
+}
+
+// admonitionOpening reports whether a node is the comment written for an
+// opening admonition fence.
+//
+// Parameters:
+//   - n: Node to check
+//
+// Returns:
+//   - string: Admonition kind, lowercased (e.g., "note")
+//   - string: Title given after the kind, or empty
+//   - bool: false if n does not open an admonition
+func admonitionOpening(n *html.Node) (string, string, bool) {
+	if n.Type != html.CommentNode {
+		return "", "", false
+	}
+	match := admonitionComment.FindStringSubmatch(n.Data)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
+
+// isAdmonitionClosing reports whether a node is the comment written for a
+// closing admonition fence.
+func isAdmonitionClosing(n *html.Node) bool {
+	return n.Type == html.CommentNode && strings.TrimSpace(n.Data) == admonitionEnd
+}
+
+// blockquoteAlert detects a GitHub-style alert: a blockquote whose first
+// paragraph starts with a marker such as "[!NOTE]" or "[!WARNING]". The
+// marker is removed from the blockquote, along with its paragraph if
+// nothing else is left in it.
+//
+// Parameters:
+//   - n: Blockquote element
+//
+// Returns:
+//   - calloutKind: The alert's callout kind
+//   - bool: false if the blockquote is not an alert; it is left unchanged
+func blockquoteAlert(n *html.Node) (calloutKind, bool) {
+	first := firstElementChild(n)
+	if first == nil || first.Data != "p" || first.FirstChild == nil || first.FirstChild.Type != html.TextNode {
+		return calloutKind{}, false
+	}
+	text := first.FirstChild
+	match := admonitionMarker.FindStringSubmatch(text.Data)
+	if match == nil {
+		return calloutKind{}, false
+	}
+	kind, ok := calloutKinds[strings.ToLower(match[1])]
+	if !ok {
+		return calloutKind{}, false
+	}
+
+	text.Data = text.Data[len(match[0]):]
+	if text.Data == "" {
+		first.RemoveChild(text)
+	}
+	if strings.TrimSpace(getTextContent(first)) == "" && findDescendant(first, "img") == nil {
+		n.RemoveChild(first)
+	}
+	return kind, true
+}
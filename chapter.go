@@ -2,15 +2,17 @@
 package bookie
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/fs"
 	"log"
-	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // File system constants define expected file extensions and naming patterns.
@@ -32,39 +34,129 @@ var (
 
 	// ErrInvalidChapter indicates a chapter directory is malformed
 	ErrInvalidChapter = errors.New("invalid chapter directory")
+
+	// ErrDuplicateEpisode indicates two source directories contain the
+	// same episode number
+	ErrDuplicateEpisode = errors.New("duplicate episode number")
 )
 
+// chapterConfigFiles are the names of an optional markdown file in an
+// episode directory whose front matter configures the chapter.
+var chapterConfigFiles = []string{"chapter.md", "_meta.md"}
+
 // episodeNumberPattern matches and extracts episode numbers from directory names.
 // Example: "Episode 1" -> "1"
 var episodeNumberPattern = regexp.MustCompile(`Episode\s*(\d+)`)
 
-// getChapters scans the root directory for episode folders and builds an ordered
-// slice of chapters for processing.
+// getChapters scans the root directory and any additional source
+// directories for episode folders and builds an ordered slice of chapters
+// for processing.
 //
 // Returns:
-//   - []Chapter: Ordered slice of chapters found in the root directory
-//   - error: Root directory validation or scanning errors
+//   - []Chapter: Ordered slice of chapters found in all source directories
+//   - error: Directory validation or scanning errors
 //
 // Errors:
-//   - ErrInvalidRoot if root directory is invalid
-//   - ErrNoChapters if no valid chapters are found
+//   - ErrInvalidRoot if a source directory is invalid
+//   - ErrNoChapters if no valid chapters are found, or none are in range
+//   - ErrDuplicateEpisode if two sources share an episode number
 //
 // The chapters are sorted by episode number extracted from directory names.
+// Chapters added with AddChapterContent are returned instead, in the order
+// added, when there are any. Only chapters in the range set with
+// SetChapterRange are returned.
 func (bc *BookCompiler) getChapters() ([]Chapter, error) {
-	if err := bc.validateRootDir(); err != nil {
-		return nil, fmt.Errorf("root directory validation failed: %w", err)
+	defer bc.trackPhase(&bc.timings.Discovery, time.Now())
+
+	if len(bc.contentChapters) > 0 {
+		chapters := bc.filterChapterRange(append([]Chapter(nil), bc.contentChapters...))
+		if len(chapters) == 0 {
+			return nil, ErrNoChapters
+		}
+		return chapters, nil
 	}
 
-	chapters, err := bc.collectChapters()
-	if err != nil {
-		return nil, err
+	var chapters []Chapter
+	for _, root := range append([]string{bc.RootDir}, bc.sources...) {
+		if err := bc.validateRootDir(root); err != nil {
+			return nil, fmt.Errorf("root directory validation failed: %w", err)
+		}
+
+		found, err := bc.collectChapters(root)
+		if err != nil {
+			return nil, err
+		}
+		chapters = append(chapters, found...)
+	}
+
+	if len(chapters) == 0 {
+		return nil, ErrNoChapters
+	}
+	if len(bc.sources) > 0 {
+		if err := checkDuplicateEpisodes(chapters); err != nil {
+			return nil, err
+		}
 	}
 
 	bc.sortChapters(chapters)
+	chapters = bc.filterChapterRange(chapters)
+	if len(chapters) == 0 {
+		return nil, ErrNoChapters
+	}
 	return chapters, nil
 }
 
-// validateRootDir ensures the root directory exists and is accessible.
+// filterChapterRange keeps the chapters whose episode numbers are within
+// the range set with SetChapterRange.
+//
+// Parameters:
+//   - chapters: Chapters in book order
+//
+// Returns:
+//   - []Chapter: The chapters in range, in the same order
+func (bc *BookCompiler) filterChapterRange(chapters []Chapter) []Chapter {
+	if bc.chapterRangeFrom <= 0 && bc.chapterRangeTo <= 0 {
+		return chapters
+	}
+
+	var kept []Chapter
+	for _, chapter := range chapters {
+		number := extractEpisodeNumber(chapter.Path)
+		if number < bc.chapterRangeFrom || (bc.chapterRangeTo > 0 && number > bc.chapterRangeTo) {
+			bc.logDebug("Skipping chapter outside range: %s", chapter.Path)
+			continue
+		}
+		kept = append(kept, chapter)
+	}
+	return kept
+}
+
+// checkDuplicateEpisodes reports chapters from different source
+// directories that share an episode number, since their order would be
+// ambiguous, and chapters found twice because a source was added twice.
+//
+// Parameters:
+//   - chapters: Chapters collected from all sources
+//
+// Returns:
+//   - error: ErrDuplicateEpisode naming both chapters, or nil
+func checkDuplicateEpisodes(chapters []Chapter) error {
+	seen := make(map[int]string)
+	for _, chapter := range chapters {
+		number := extractEpisodeNumber(chapter.Path)
+		other, ok := seen[number]
+		if ok && (other == chapter.Path || filepath.Dir(other) != filepath.Dir(chapter.Path)) {
+			return fmt.Errorf("%w %d: %s and %s", ErrDuplicateEpisode, number, other, chapter.Path)
+		}
+		seen[number] = chapter.Path
+	}
+	return nil
+}
+
+// validateRootDir ensures a source directory exists and is accessible.
+//
+// Parameters:
+//   - root: Directory to validate
 //
 // Returns:
 //   - error: Validation errors including access and type checks
@@ -73,70 +165,80 @@ func (bc *BookCompiler) getChapters() ([]Chapter, error) {
 // 1. Non-empty string path
 // 2. Existing directory
 // 3. Accessible with current permissions
-func (bc *BookCompiler) validateRootDir() error {
-	if bc.RootDir == "" {
+func (bc *BookCompiler) validateRootDir(root string) error {
+	if root == "" {
 		return ErrInvalidRoot
 	}
 
-	info, err := os.Stat(bc.RootDir)
+	info, err := bc.stat(root)
 	if err != nil {
 		return fmt.Errorf("failed to access root directory: %w", err)
 	}
 
 	if !info.IsDir() {
-		return fmt.Errorf("%s is not a directory", bc.RootDir)
+		return fmt.Errorf("%s is not a directory", root)
 	}
 
 	return nil
 }
 
-// collectChapters gathers and validates all episode chapters from the root directory.
+// collectChapters gathers and validates all episode chapters from a source directory.
+//
+// Parameters:
+//   - root: Source directory to scan
 //
 // Returns:
-//   - []Chapter: Slice of valid chapters found
+//   - []Chapter: Slice of valid chapters found, possibly empty
 //   - error: Directory reading or validation errors
 //
 // Each directory entry is processed if it:
 // 1. Is a directory
 // 2. Contains the episode prefix
 // 3. Contains at least one markdown file
-func (bc *BookCompiler) collectChapters() ([]Chapter, error) {
+// 4. Is not excluded by SetIgnorePatterns or the source's .bookieignore
+func (bc *BookCompiler) collectChapters(root string) ([]Chapter, error) {
 	var chapters []Chapter
 
-	entries, err := os.ReadDir(bc.RootDir)
+	if err := bc.loadIgnoreRules(root); err != nil {
+		return nil, err
+	}
+
+	entries, err := bc.readDir(root)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read directory: %w", err)
 	}
 
 	for _, entry := range entries {
-		if chapter, ok := bc.processDirectoryEntry(entry); ok {
+		if chapter, ok := bc.processDirectoryEntry(root, entry); ok {
 			chapters = append(chapters, chapter)
 		}
 	}
 
-	if len(chapters) == 0 {
-		return nil, ErrNoChapters
-	}
-
 	return chapters, nil
 }
 
 // processDirectoryEntry validates and processes a single directory entry into a Chapter.
 //
 // Parameters:
+//   - root: Source directory containing the entry
 //   - entry: Directory entry to process
 //
 // Returns:
 //   - Chapter: Processed chapter if valid
 //   - bool: true if entry was processed successfully
 //
-// Handles image discovery and markdown file collection for each chapter.
-func (bc *BookCompiler) processDirectoryEntry(entry fs.DirEntry) (Chapter, bool) {
+// Handles image discovery, markdown file collection, and front matter
+// parsing for each chapter.
+func (bc *BookCompiler) processDirectoryEntry(root string, entry fs.DirEntry) (Chapter, bool) {
 	if !entry.IsDir() || !strings.Contains(entry.Name(), episodePrefix) {
 		return Chapter{}, false
 	}
 
-	chapterPath := filepath.Join(bc.RootDir, entry.Name())
+	chapterPath := filepath.Join(root, entry.Name())
+	if bc.isIgnored(chapterPath, true) {
+		bc.logDebug("Ignoring chapter: %s", entry.Name())
+		return Chapter{}, false
+	}
 	files, err := bc.getMarkdownFiles(chapterPath)
 	if err != nil {
 		bc.logWarning("Skipping chapter %s: %v", entry.Name(), err)
@@ -144,23 +246,99 @@ func (bc *BookCompiler) processDirectoryEntry(entry fs.DirEntry) (Chapter, bool)
 	}
 
 	images := make(map[string]string)
-	filepath.Walk(chapterPath, func(path string, info fs.FileInfo, err error) error {
+	bc.walkDir(chapterPath, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return nil
 		}
-		if !info.IsDir() && isImageFile(path) {
+		if bc.isIgnored(path, entry.IsDir()) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !entry.IsDir() && isImageFile(path) {
 			images[filepath.Base(path)] = path
 		}
 		return nil
 	})
 
+	files, config, err := bc.applyChapterConfig(files)
+	if err != nil {
+		bc.logWarning("Skipping chapter %s: %v", entry.Name(), err)
+		return Chapter{}, false
+	}
+
+	meta, err := bc.readFrontMatter(files[0])
+	if err != nil {
+		bc.logWarning("Skipping chapter %s: %v", entry.Name(), err)
+		return Chapter{}, false
+	}
+	for key, value := range config {
+		if meta == nil {
+			meta = make(map[string]string)
+		}
+		meta[key] = value
+	}
+
 	return Chapter{
 		Path:   chapterPath,
+		Title:  meta[titleField],
 		Files:  files,
 		Images: images,
+		Meta:   meta,
 	}, true
 }
 
+// applyChapterConfig finds a chapter configuration file (chapter.md or
+// _meta.md) among a chapter's markdown files. A configuration file with
+// content after its front matter is moved to the front to render as the
+// chapter introduction; one with only front matter is removed.
+//
+// Parameters:
+//   - files: Sorted markdown files of the chapter
+//
+// Returns:
+//   - []string: Markdown files to render
+//   - map[string]string: Front matter of the configuration file, nil if
+//     there is none
+//   - error: File reading errors, or ErrNoMarkdown if the configuration
+//     file was the only file and has no content
+func (bc *BookCompiler) applyChapterConfig(files []string) ([]string, map[string]string, error) {
+	for i, file := range files {
+		if !isChapterConfigFile(file) {
+			continue
+		}
+
+		content, err := bc.readFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		config, body := splitFrontMatter(content)
+
+		rest := append(append([]string(nil), files[:i]...), files[i+1:]...)
+		if len(bytes.TrimSpace(body)) > 0 {
+			rest = append([]string{file}, rest...)
+		}
+		if len(rest) == 0 {
+			return nil, nil, ErrNoMarkdown
+		}
+		return rest, config, nil
+	}
+	return files, nil, nil
+}
+
+// isChapterConfigFile reports whether a markdown file is a chapter
+// configuration file, by name.
+func isChapterConfigFile(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	for _, config := range chapterConfigFiles {
+		if name == config {
+			return true
+		}
+	}
+	return false
+}
+
 // isImageFile checks if a file has a supported image extension.
 //
 // Parameters:
@@ -169,10 +347,11 @@ func (bc *BookCompiler) processDirectoryEntry(entry fs.DirEntry) (Chapter, bool)
 // Returns:
 //   - bool: true if file has a supported image extension
 //
-// Supported extensions: .jpg, .jpeg, .png, .gif
+// Supported extensions: .jpg, .jpeg, .png, .gif, .svg. SVG images are
+// only drawn when a rasterizer is set with SetSVGRasterizer.
 func isImageFile(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
-	return ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".gif"
+	return ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".gif" || ext == svgExtension
 }
 
 // getMarkdownFiles retrieves all markdown files from a directory.
@@ -186,7 +365,7 @@ func isImageFile(path string) bool {
 //
 // Files are sorted alphabetically for consistent processing order.
 func (bc *BookCompiler) getMarkdownFiles(path string) ([]string, error) {
-	entries, err := os.ReadDir(path)
+	entries, err := bc.readDir(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read chapter directory: %w", err)
 	}
@@ -200,7 +379,8 @@ func (bc *BookCompiler) getMarkdownFiles(path string) ([]string, error) {
 	return files, nil
 }
 
-// collectMarkdownFiles filters and collects markdown files from directory entries.
+// collectMarkdownFiles filters and collects markdown files from directory
+// entries, leaving out ignored files.
 //
 // Parameters:
 //   - entries: Directory entries to process
@@ -213,6 +393,10 @@ func (bc *BookCompiler) collectMarkdownFiles(entries []fs.DirEntry, basePath str
 	for _, entry := range entries {
 		if isMarkdownFile(entry) {
 			filePath := filepath.Join(basePath, entry.Name())
+			if bc.isIgnored(filePath, false) {
+				bc.logDebug("Ignoring markdown file: %s", entry.Name())
+				continue
+			}
 			files = append(files, filePath)
 			bc.logDebug("Found markdown file: %s", entry.Name())
 		}
@@ -234,18 +418,34 @@ func isMarkdownFile(entry fs.DirEntry) bool {
 	)
 }
 
-// sortChapters sorts chapters by their episode numbers in ascending order.
+// sortChapters sorts chapters by the "weight" field of their front
+// matter, then by their episode numbers, in ascending order. Chapters
+// without a weight have weight 0.
 //
 // Parameters:
 //   - chapters: Slice of chapters to sort in-place
 func (bc *BookCompiler) sortChapters(chapters []Chapter) {
-	sort.Slice(chapters, func(i, j int) bool {
+	sort.SliceStable(chapters, func(i, j int) bool {
+		weightI, weightJ := chapterWeight(chapters[i]), chapterWeight(chapters[j])
+		if weightI != weightJ {
+			return weightI < weightJ
+		}
 		numI := extractEpisodeNumber(chapters[i].Path)
 		numJ := extractEpisodeNumber(chapters[j].Path)
 		return numI < numJ
 	})
 }
 
+// chapterWeight returns the ordering weight from a chapter's front
+// matter, or 0 if it has none or it is not an integer.
+func chapterWeight(chapter Chapter) int {
+	weight, err := strconv.Atoi(chapter.Meta[weightField])
+	if err != nil {
+		return 0
+	}
+	return weight
+}
+
 // logWarning logs a warning message with formatting.
 //
 // Parameters:
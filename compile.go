@@ -6,11 +6,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/jung-kurt/gofpdf"
 	"github.com/russross/blackfriday/v2"
 	"golang.org/x/net/html"
 )
@@ -25,6 +26,10 @@ var (
 
 	// ErrNilChapter indicates a nil or invalid chapter was provided
 	ErrNilChapter = errors.New("nil chapter provided")
+
+	// ErrUnresolvedImages indicates images were replaced by placeholders
+	// because they could not be found or loaded (see SetContinueOnError)
+	ErrUnresolvedImages = errors.New("unresolved images")
 )
 
 // PDF document formatting constants define the layout and styling parameters.
@@ -40,12 +45,80 @@ const (
 	pageNumSize    = 8.0     // Font size for page numbers
 	pageNumYOffset = -15.0   // Vertical offset for page numbers
 
+	defaultPageNumFormat = "Page %d" // Default page number text
+	totalPagesAlias      = "{nb}"    // Placeholder replaced by the page count
+
+	headerFont       = "Arial" // Font for running headers
+	headerStyle      = "I"     // Italic style for running headers
+	headerSize       = 9.0     // Font size for running headers
+	headerLineHeight = 5.0     // Height of the running header line
+	headerRuleOffset = 5.0     // Distance of the header rule above the top margin
+
+	defaultHeaderVerso = "{book}"    // Running header template for even pages
+	defaultHeaderRecto = "{chapter}" // Running header template for odd pages
+	continuationSuffix = " (continued)"
+
+	defaultWatermarkOpacity = 0.15 // Default watermark alpha
+	watermarkFontSize       = 72.0 // Font size for watermark text
+	watermarkAngle          = 45.0 // Counter-clockwise rotation in degrees
+	watermarkGray           = 128  // Gray level for watermark text
+	watermarkImageScale     = 0.6  // Watermark image width relative to the page
+
 	chapterTitleFont  = "B"  // Bold style for chapter titles
 	chapterTitleSize  = 24.0 // Font size for chapter titles
 	chapterLineHeight = 10.0 // Line spacing for chapter titles
 	chapterSpacing    = 20.0 // Space after chapter titles
+
+	chapterLabelSize      = 14.0 // Font size of the label above chapter titles
+	chapterLabelHeight    = 8.0  // Line height of the label above chapter titles
+	chapterSubtitleSize   = 16.0 // Font size of the subtitle below chapter titles
+	chapterSubtitleHeight = 9.0  // Line height of the subtitle below chapter titles
+	chapterAuthorSize     = 12.0 // Font size of the author below chapter titles
+	chapterAuthorHeight   = 7.0  // Line height of the author below chapter titles
+	chapterRuleRatio      = 0.3  // Width of the rule below chapter titles relative to the content width
+	chapterRuleOffset     = 3.0  // Gap between a chapter title and its rule
+	chapterRuleWeight     = 0.5  // Line width of the rule below chapter titles
+
+	tocSummarySize       = 9.0 // Font size of chapter summaries in the ToC
+	tocSummaryLineHeight = 4.0 // Line height of chapter summaries in the ToC
+
+	defaultKeepWithNext     = 3   // Body lines kept on the same page as a heading
+	defaultParagraphSpacing = 7.5 // Space before each paragraph
+
+	defaultHeadingSpaceAfter = 10.0 // Space below headings
+	defaultBlockquoteBar     = 1.0  // Width of the blockquote accent bar
+
+	coverField     = "cover"    // Front matter field naming a chapter cover image
+	summaryField   = "summary"  // Front matter field with a chapter's ToC summary
+	stylesField    = "styles"   // Front matter field naming a chapter styles file
+	titleField     = "title"    // Front matter field overriding a chapter's title
+	authorField    = "author"   // Front matter field with a chapter's author
+	weightField    = "weight"   // Front matter field ordering chapters
+	appendixPrefix = "Appendix" // Title prefix for lettered back matter
+
+	figureLabelPrefix = "Figure" // Caption prefix for numbered images
+	tableLabelPrefix  = "Table"  // Caption prefix for numbered tables
 )
 
+// deterministicDate is the creation and modification date stamped on
+// PDFs in deterministic mode.
+var deterministicDate = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// defaultMarkdownExtensions are the markdown extensions enabled unless
+// changed with SetMarkdownExtensions.
+const defaultMarkdownExtensions = blackfriday.CommonExtensions | blackfriday.AutoHeadingIDs
+
+// smartPunctuationFlags are the blackfriday renderer flags that turn
+// straight quotes, dashes, and the fractions 1/2, 1/4, and 3/4 into
+// typographic characters. SmartypantsFractions is left out: it writes
+// other fractions with sup and sub elements, which are not rendered.
+const smartPunctuationFlags = blackfriday.Smartypants | blackfriday.SmartypantsDashes |
+	blackfriday.SmartypantsLatexDashes
+
+// defaultHTMLFlags are the blackfriday renderer flags used unless smart
+// punctuation is enabled with SetSmartPunctuation.
+const defaultHTMLFlags = blackfriday.CommonHTMLFlags &^ (smartPunctuationFlags | blackfriday.SmartypantsFractions)
+
 // Compile generates a complete PDF document from the organized markdown files.
 // It performs two passes:
 // 1. Generates table of contents
@@ -63,6 +136,9 @@ func (bc *BookCompiler) Compile() error {
 	if err := bc.validateCompilerState(); err != nil {
 		return fmt.Errorf("invalid compiler state: %w", err)
 	}
+	bc.timings = PhaseTimings{}
+	bc.unresolvedImages = nil
+	defer bc.removeRemoteImages()
 
 	if err := bc.generateTableOfContents(); err != nil {
 		return fmt.Errorf("failed to generate table of contents: %w", err)
@@ -72,7 +148,66 @@ func (bc *BookCompiler) Compile() error {
 		return fmt.Errorf("failed to generate content: %w", err)
 	}
 
-	return bc.pdf.OutputFileAndClose(bc.OutputPath)
+	start := time.Now()
+	err := bc.pdf.OutputFileAndClose(bc.OutputPath)
+	bc.trackPhase(&bc.timings.Output, start)
+	if err != nil {
+		return err
+	}
+	return bc.unresolvedImagesError()
+}
+
+// unresolvedImagesError reports the images replaced by placeholders with
+// continue-on-error enabled.
+//
+// Returns:
+//   - error: ErrUnresolvedImages listing the images, or nil if there are
+//     none
+func (bc *BookCompiler) unresolvedImagesError() error {
+	if len(bc.unresolvedImages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnresolvedImages, strings.Join(bc.unresolvedImages, "; "))
+}
+
+// CompileSplit renders each chapter to its own PDF file in outDir, named
+// after the chapter directory (e.g., "Episode01.pdf"). Each file contains
+// only that chapter, with the same fonts and styles as the full book.
+// Call Compile as well to produce the combined book.
+//
+// Parameters:
+//   - outDir: Output directory, created if it does not exist
+//
+// Returns:
+//   - error: Chapter discovery, rendering, or file output errors, or,
+//     with SetContinueOnError, ErrUnresolvedImages listing the missing
+//     images of all chapters once every file is written
+func (bc *BookCompiler) CompileSplit(outDir string) error {
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	bc.unresolvedImages = nil
+	defer bc.removeRemoteImages()
+
+	chapters, err := bc.getChapters()
+	if err != nil {
+		return fmt.Errorf("failed to get chapters: %w", err)
+	}
+	bc.startProgress(chapters, 0)
+
+	for _, chapter := range chapters {
+		bc.initializePDF()
+		if err := bc.processChapter(chapter); err != nil {
+			return fmt.Errorf("failed to process chapter %s: %w", chapter.Path, err)
+		}
+
+		outPath := filepath.Join(outDir, filepath.Base(chapter.Path)+".pdf")
+		if err := bc.pdf.OutputFileAndClose(outPath); err != nil {
+			return fmt.Errorf("failed to write %s: %w", outPath, err)
+		}
+	}
+
+	return bc.unresolvedImagesError()
 }
 
 // validateCompilerState ensures all required compiler settings are configured.
@@ -101,64 +236,366 @@ func (bc *BookCompiler) generateTableOfContents() error {
 	return nil
 }
 
-// ensureChapterBreak adds proper spacing between chapters.
-// Always starts a new page and adds vertical spacing.
-func (bc *BookCompiler) ensureChapterBreak() {
+// ensureChapterBreak starts the page a chapter, section, or index opens
+// on, adds the space above its title, and bookmarks it at the top level
+// of the PDF outline. A page break pending from the end of the previous
+// content is dropped, as the new page replaces it.
+//
+// Parameters:
+//   - title: Title of the chapter, section, or index
+func (bc *BookCompiler) ensureChapterBreak(title string) {
+	bc.pageBreakPending = false
+	bc.chapterOpening = true
+	bc.chapterStartPage = bc.pdf.PageNo() + 1
 	bc.pdf.AddPage()
 	bc.pdf.Ln(20)
+	bc.addBookmark(title, 0)
+}
+
+// addBookmark adds an entry to the PDF outline at the current position.
+// An entry more than one level below the previous one is raised, as the
+// outline cannot skip levels.
+//
+// Parameters:
+//   - title: Entry text
+//   - level: Outline level, 0 for chapters and sections
+func (bc *BookCompiler) addBookmark(title string, level int) {
+	level = min(level, bc.bookmarkDepth)
+	bc.bookmarkDepth = level + 1
+	bc.pdf.Bookmark(strings.TrimSpace(bc.cleanText(title)), level, -1)
+}
+
+// appendixHeadingNumber numbers an h2-h6 heading of the appendix being
+// collected or rendered after the appendix letter, e.g. "A.2" for its
+// second h2 and "A.2.1" for the first h3 below that.
+//
+// Parameters:
+//   - level: Heading level
+//
+// Returns:
+//   - string: The number, or empty outside appendices and for h1
+func (bc *BookCompiler) appendixHeadingNumber(level int) string {
+	section, ok := bc.currentChapter.(matterSection)
+	if !ok || section.letter == "" || level < 2 || level >= len(bc.headingCounts) {
+		return ""
+	}
+	bc.headingCounts[level]++
+	for deeper := level + 1; deeper < len(bc.headingCounts); deeper++ {
+		bc.headingCounts[deeper] = 0
+	}
+
+	number := section.letter
+	for l := 2; l <= level; l++ {
+		number += "." + strconv.Itoa(bc.headingCounts[l])
+	}
+	return number
 }
 
 // generateContent performs the second pass to create the final PDF content.
-// Includes table of contents and all chapters with proper formatting.
+// Includes the optional table of contents, front matter, all chapters,
+// back matter, and the index of marked terms with proper formatting.
 //
 // Returns:
 //   - error: Content generation errors
 //
-// Ensures chapters start on even pages for proper book layout.
+// Chapters after the first start on odd (right-hand) pages, after a blank
+// filler page when needed.
 func (bc *BookCompiler) generateContent() error {
 	bc.initializePDF()
-	bc.generateToC()
+	if bc.generateToCPage {
+		bc.generateToC()
+	}
 
 	chapters, err := bc.getChapters()
 	if err != nil {
 		return fmt.Errorf("failed to get chapters: %w", err)
 	}
+	bc.startProgress(chapters, len(bc.frontMatter)+len(bc.backMatter))
+
+	for _, section := range bc.frontMatter {
+		if err := bc.processMatterSection(section); err != nil {
+			return fmt.Errorf("failed to process section %s: %w", section.title, err)
+		}
+	}
 
 	for i, chapter := range chapters {
+		if i > 0 && bc.pdf.PageNo()%2 != 0 {
+			bc.pageBreakPending = false
+			bc.pdf.AddPage()
+		}
 		if err := bc.processChapter(chapter); err != nil {
 			return fmt.Errorf("failed to process chapter %s: %w", chapter.Path, err)
 		}
+	}
 
-		// Ensure chapters start on even pages
-		if i < len(chapters)-1 && bc.pdf.PageNo()%2 != 0 {
-			bc.pdf.AddPage()
+	for _, section := range bc.backMatter {
+		if err := bc.processMatterSection(section); err != nil {
+			return fmt.Errorf("failed to process section %s: %w", section.title, err)
 		}
 	}
 
+	if len(bc.indexTerms) > 0 {
+		bc.renderIndex()
+	}
+
 	return nil
 }
 
 // initializePDF creates a new PDF document with standard settings.
-// Configures page size, margins, and optional page numbering.
+// Configures page size, margins, and the page header and footer hooks.
 func (bc *BookCompiler) initializePDF() {
-	bc.pdf = gofpdf.New(pdfOrientation, pdfUnit, pdfFormat, "")
+	bc.pdf = bc.newPDF()
 	bc.pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	bc.pdf.SetTextColor(bc.textColor.r, bc.textColor.g, bc.textColor.b)
+	if bc.taggedPDF || bc.language != "" {
+		bc.writeLanguageMetadata()
+	}
+	if bc.pageLayout != "" || bc.initialZoom != "" {
+		bc.setDisplayMode()
+	}
+	bc.pdf.SetCompression(bc.compression)
+	if p := bc.protection; p != nil {
+		bc.pdf.SetProtection(p.permissions, p.userPassword, p.ownerPassword)
+	}
+	if bc.deterministic {
+		bc.pdf.SetCreationDate(deterministicDate)
+		bc.pdf.SetModificationDate(deterministicDate)
+		bc.pdf.SetCatalogSort(true)
+	}
+	if bc.glyphFallbackFont != "" {
+		bc.registerFallbackFont()
+	}
+	if bc.hyphenation != "" && bc.activeHyphenator() == nil {
+		bc.logWarning("No hyphenation patterns for language %q; load them with LoadHyphenationPatterns", bc.hyphenation)
+	}
+	bc.figureCount, bc.tableCount = 0, 0
+	bc.currentChapter, bc.chapterStartPage, bc.coverPage = nil, 0, 0
+	bc.bodyStartPage = 0
+	bc.indexTerms = make(map[string][]int)
+	bc.missingAltText = nil
+	bc.font = TextState{}
+	bc.pageBreakPending = false
+	bc.bookmarkDepth = 0
+	bc.unsupportedGlyphs = make(map[rune]bool)
+	bc.anchorLinks = make(map[string]int)
 
+	bc.pdf.AliasNbPages(totalPagesAlias)
+	bc.pdf.SetHeaderFuncMode(bc.renderHeader, true)
+	bc.pdf.SetFooterFunc(bc.renderFooter)
+}
+
+// setDisplayMode applies the configured page layout and initial zoom.
+// Unknown values are reported by gofpdf when the document is written.
+func (bc *BookCompiler) setDisplayMode() {
+	zoom := bc.initialZoom
+	if zoom == "" {
+		zoom = "default"
+	}
+	layout, ok := pageLayoutModes[bc.pageLayout]
+	if !ok {
+		layout = bc.pageLayout
+	}
+	bc.pdf.SetDisplayMode(zoom, layout)
+}
+
+// registerFallbackFont loads the glyph fallback TrueType font into the PDF.
+// The file is read directly because gofpdf resolves font paths relative
+// to its font directory. Load errors are recorded on the PDF and reported
+// when the document is written.
+func (bc *BookCompiler) registerFallbackFont() {
+	fontBytes, err := os.ReadFile(bc.glyphFallbackPath)
+	if err != nil {
+		bc.pdf.SetError(fmt.Errorf("failed to load fallback font: %w", err))
+		return
+	}
+	bc.pdf.AddUTF8FontFromBytes(bc.glyphFallbackFont, fontStyleNormal, fontBytes)
+}
+
+// renderHeader draws the page header at the top of each page.
+// Invoked by gofpdf whenever a new page is added. The page background is
+// filled first; chapter-opening and cover pages are left without a header.
+func (bc *BookCompiler) renderHeader() {
+	if bc.backgroundColor != nil {
+		bc.drawBackground()
+	}
+	if page := bc.pdf.PageNo(); page == bc.chapterStartPage || page == bc.coverPage {
+		return
+	}
+
+	if bc.runningHeaders {
+		bc.drawRunningHeader()
+	}
+	if bc.headerRule {
+		bc.drawHeaderRule()
+	}
+}
+
+// drawBackground fills the current page with the background color.
+func (bc *BookCompiler) drawBackground() {
+	width, height := bc.pdf.GetPageSize()
+	bc.pdf.SetFillColor(bc.backgroundColor.r, bc.backgroundColor.g, bc.backgroundColor.b)
+	bc.pdf.Rect(0, 0, width, height, "F")
+}
+
+// drawRunningHeader writes the running header text for the current page.
+// Verso pages are left-aligned and recto pages right-aligned, so the text
+// sits on the outer edge of a spread.
+func (bc *BookCompiler) drawRunningHeader() {
+	template, align := bc.headerRecto, AlignRight
+	if bc.pdf.PageNo()%2 == 0 {
+		template, align = bc.headerVerso, AlignLeft
+	}
+
+	text := bc.runningHeaderText(template)
+	if text == "" {
+		return
+	}
+
+	// gofpdf restores the page's font after the header is drawn
+	previous := bc.font
+	defer func() { bc.font = previous }()
+
+	width, _ := bc.pdf.GetPageSize()
+	bc.setFont(headerFont, headerStyle, headerSize)
+	bc.pdf.SetXY(pdfMargin, pdfMargin-headerRuleOffset-headerLineHeight)
+	bc.cellWithFallback(width-2*pdfMargin, headerLineHeight, text, 0, align, 0)
+}
+
+// currentChapterTitle returns the title of the chapter or matter section
+// being rendered, or an empty string outside of any chapter.
+func (bc *BookCompiler) currentChapterTitle() string {
+	switch current := bc.currentChapter.(type) {
+	case Chapter:
+		return chapterTitle(current)
+	case matterSection:
+		return current.title
+	}
+	return ""
+}
+
+// runningHeaderText expands a running header template for the current page.
+//
+// Parameters:
+//   - template: Header template containing optional placeholders
+//
+// Returns:
+//   - string: Normalized header text, empty if nothing remains after
+//     expansion
+func (bc *BookCompiler) runningHeaderText(template string) string {
+	chapterTitle := bc.currentChapterTitle()
+	if chapterTitle != "" && bc.continuationNote && bc.pdf.PageNo() > bc.chapterStartPage {
+		chapterTitle += continuationSuffix
+	}
+
+	text := strings.NewReplacer(
+		"{book}", bc.bookTitle,
+		"{chapter}", chapterTitle,
+		"{page}", bc.pageLabel(bc.pdf.PageNo(), bc.bodyStartPage),
+	).Replace(template)
+	return bc.normalizeText(text)
+}
+
+// drawHeaderRule draws a horizontal rule across the content width just
+// above the top margin, using the configured weight and color.
+func (bc *BookCompiler) drawHeaderRule() {
+	y := pdfMargin - headerRuleOffset
+	width, _ := bc.pdf.GetPageSize()
+	bc.pdf.SetLineWidth(bc.headerRuleWeight)
+	bc.pdf.SetDrawColor(bc.headerRuleColor.r, bc.headerRuleColor.g, bc.headerRuleColor.b)
+	bc.pdf.Line(pdfMargin, y, width-pdfMargin, y)
+}
+
+// renderFooter draws the page overlay and footer at the end of each page.
+// The watermark is drawn first so page numbers remain unaffected by it.
+func (bc *BookCompiler) renderFooter() {
+	bc.drawWatermark()
 	if bc.pageNumbers {
-		bc.setupPageNumbers()
+		bc.renderPageNumber()
 	}
 }
 
-// setupPageNumbers configures the page numbering footer function.
-// Adds centered page numbers at the bottom of each page.
-func (bc *BookCompiler) setupPageNumbers() {
-	bc.pdf.SetFooterFunc(func() {
-		bc.pdf.SetY(pageNumYOffset)
-		bc.pdf.SetFont(pageNumFont, pageNumStyle, pageNumSize)
-		bc.pdf.CellFormat(0, chapterLineHeight,
-			fmt.Sprintf("Page %d", bc.pdf.PageNo()),
-			"", 0, "C", false, 0, "")
-	})
+// renderPageNumber adds the formatted page number at the bottom of the page
+// using the configured font and alignment.
+func (bc *BookCompiler) renderPageNumber() {
+	style := bc.pageNumberStyle
+	bc.pdf.SetY(pageNumYOffset)
+	bc.pdf.SetFont(style.FontFamily, style.Style, style.Size)
+	bc.pdf.CellFormat(0, chapterLineHeight,
+		formatPageNumber(bc.pageNumberFormat, bc.pageLabel(bc.pdf.PageNo(), bc.bodyStartPage)),
+		"", 0, style.Alignment, false, 0, "")
+}
+
+// formatPageNumber expands a page number format for the given page.
+//
+// Parameters:
+//   - format: Format containing up to two %d verbs
+//   - page: Displayed page number (Arabic or Roman)
+//
+// Returns:
+//   - string: Page number text, with the second %d replaced by the total
+//     pages alias that gofpdf substitutes when the document is closed
+func formatPageNumber(format, page string) string {
+	text := strings.Replace(format, "%d", page, 1)
+	return strings.Replace(text, "%d", totalPagesAlias, 1)
+}
+
+// drawWatermark stamps the configured watermark text and image onto the
+// current page at the watermark opacity. Text is rotated diagonally
+// around the page center; images are centered without rotation.
+func (bc *BookCompiler) drawWatermark() {
+	if bc.watermarkText == "" && bc.watermarkImage == "" {
+		return
+	}
+
+	width, height := bc.pdf.GetPageSize()
+	cx, cy := width/2, height/2
+
+	bc.pdf.SetAlpha(bc.watermarkOpacity, "Normal")
+	defer bc.pdf.SetAlpha(1, "Normal")
+
+	if bc.watermarkImage != "" {
+		if info := bc.pdf.RegisterImage(bc.watermarkImage, ""); info != nil {
+			w := width * watermarkImageScale
+			h := w * info.Height() / info.Width()
+			bc.pdf.Image(bc.watermarkImage, cx-w/2, cy-h/2, w, h, false, "", 0, "")
+		}
+	}
+
+	if bc.watermarkText != "" {
+		bc.pdf.SetFont(bc.chapterFont, fontStyleBold, watermarkFontSize)
+		previous := bc.setTextColor(rgbColor{watermarkGray, watermarkGray, watermarkGray})
+
+		bc.pdf.TransformBegin()
+		bc.pdf.TransformRotate(watermarkAngle, cx, cy)
+		bc.pdf.Text(cx-bc.pdf.GetStringWidth(bc.watermarkText)/2, cy, bc.watermarkText)
+		bc.pdf.TransformEnd()
+
+		bc.restoreTextColor(previous)
+	}
+}
+
+// startProgress resets progress reporting for a compilation.
+//
+// Parameters:
+//   - chapters: Chapters that will be processed
+//   - extra: Number of additional files, such as front and back matter
+func (bc *BookCompiler) startProgress(chapters []Chapter, extra int) {
+	bc.progressDone, bc.progressTotal = 0, extra
+	for _, chapter := range chapters {
+		bc.progressTotal += len(chapter.Files)
+	}
+}
+
+// reportProgress records a completed file and invokes the progress
+// callback, if one is set.
+//
+// Parameters:
+//   - file: Path of the file that was just rendered
+func (bc *BookCompiler) reportProgress(file string) {
+	bc.progressDone++
+	if bc.progress != nil {
+		bc.progress(bc.progressDone, bc.progressTotal, file)
+	}
 }
 
 // processChapter converts a single chapter's content to PDF format.
@@ -171,6 +608,7 @@ func (bc *BookCompiler) setupPageNumbers() {
 //
 // Handles:
 // - Chapter validation
+// - Optional cover page from the chapter front matter
 // - Title rendering
 // - Content file processing
 // - Proper spacing and layout
@@ -182,23 +620,52 @@ func (bc *BookCompiler) processChapter(chapter Chapter) error {
 		return ErrEmptyChapter
 	}
 
-	bc.pdf.AddPage()
-	bc.pdf.Ln(20)
+	bc.currentChapter = chapter
+	popStyles, err := bc.pushChapterStyles(chapter)
+	if err != nil {
+		return fmt.Errorf("failed to load chapter styles: %w", err)
+	}
+	defer popStyles()
+
+	if bc.bodyStartPage == 0 {
+		bc.bodyStartPage = bc.pdf.PageNo() + 1
+	}
+
+	// Relative image paths resolve against the chapter's own files
+	bc.currentFile = chapter.Files[0]
+	if cover := chapter.Meta[coverField]; cover != "" {
+		if err := bc.renderChapterCover(cover); err != nil {
+			return fmt.Errorf("failed to render chapter cover: %w", err)
+		}
+	}
+
+	bc.ensureChapterBreak(chapterTitle(chapter))
+	bc.registerAnchor(chapterAnchor(chapter.Path))
 
-	if err := bc.renderChapterTitle(chapter.Path); err != nil {
+	subtitle := ""
+	if bc.chapterSubtitles {
+		subtitle = bc.leadingHeading(chapter.Files[0])
+		bc.skipLeadingHeading = subtitle != ""
+	}
+	if err := bc.renderChapterTitle(chapter, subtitle); err != nil {
 		return fmt.Errorf("failed to render chapter title: %w", err)
 	}
 
-	bc.currentChapter = chapter
+	bc.dropCapPending = bc.dropCaps
+	bc.chapterNumber = extractEpisodeNumber(chapter.Path)
+	if bc.figureNumbering == NumberingPerChapter {
+		bc.figureCount, bc.tableCount = 0, 0
+	}
 
 	for i, file := range chapter.Files {
 		bc.currentFile = file
 		if err := bc.processMarkdownFile(file); err != nil {
 			return fmt.Errorf("failed to process file %s: %w", file, err)
 		}
+		bc.reportProgress(file)
 
 		if i < len(chapter.Files)-1 {
-			bc.pdf.Ln(defaultLineHeight * 2)
+			bc.separateFiles()
 		}
 	}
 
@@ -206,10 +673,114 @@ func (bc *BookCompiler) processChapter(chapter Chapter) error {
 	return nil
 }
 
+// separateFiles writes the configured separator between two consecutive
+// files of a chapter.
+func (bc *BookCompiler) separateFiles() {
+	switch bc.fileSeparator {
+	case FileSeparatorNone:
+	case FileSeparatorRule:
+		bc.pdf.Ln(defaultLineHeight)
+		width, _ := bc.pdf.GetPageSize()
+		left, _, right, _ := bc.pdf.GetMargins()
+		y := bc.pdf.GetY()
+		bc.pdf.Line(left, y, width-right, y)
+		bc.pdf.Ln(defaultLineHeight)
+	case FileSeparatorPageBreak:
+		bc.renderPageBreak()
+	default:
+		bc.pdf.Ln(defaultLineHeight * 2)
+	}
+}
+
+// renderChapterCover renders a chapter cover image on its own page, scaled
+// to fill the content area while preserving its aspect ratio. With
+// continue-on-error enabled, a cover that cannot be drawn is replaced by
+// a placeholder on the cover page.
+//
+// Parameters:
+//   - cover: Image reference from the chapter front matter
+//
+// Returns:
+//   - error: Image resolution, format, or loading errors
+func (bc *BookCompiler) renderChapterCover(cover string) error {
+	bc.pageBreakPending = false
+	bc.coverPage = bc.pdf.PageNo() + 1
+	bc.pdf.AddPage()
+	if err := bc.drawChapterCover(cover); err != nil {
+		return bc.unresolvedImage(cover, err)
+	}
+	return nil
+}
+
+// drawChapterCover draws a chapter cover image centered on the current
+// page.
+//
+// Parameters:
+//   - cover: Image reference from the chapter front matter
+//
+// Returns:
+//   - error: Image resolution, format, or loading errors
+func (bc *BookCompiler) drawChapterCover(cover string) error {
+	imagePath, err := bc.resolveImagePath(cover)
+	if err != nil {
+		return err
+	}
+	if !isJPEGImage(imagePath) {
+		return fmt.Errorf("unsupported image format: %s", imagePath)
+	}
+
+	pageWidth, pageHeight := bc.pdf.GetPageSize()
+	maxWidth := pageWidth - 2*pdfMargin
+	imgInfo := bc.registerImage(imagePath, maxWidth)
+	if imgInfo == nil {
+		return fmt.Errorf("failed to load image: %s", imagePath)
+	}
+
+	maxHeight := pageHeight - 2*pdfMargin
+
+	width := maxWidth
+	height := width * imgInfo.Height() / imgInfo.Width()
+	if height > maxHeight {
+		height = maxHeight
+		width = height * imgInfo.Width() / imgInfo.Height()
+	}
+
+	x := (pageWidth - width) / 2
+	y := (pageHeight - height) / 2
+	bc.pdf.Image(imagePath, x, y, width, height, false, "", 0, "")
+	return nil
+}
+
+// processMatterSection renders a front or back matter section on a new
+// page, headed by its title. Only the headings of appendices are
+// numbered.
+//
+// Parameters:
+//   - section: Section title and markdown file
+//
+// Returns:
+//   - error: File processing errors
+func (bc *BookCompiler) processMatterSection(section matterSection) error {
+	bc.currentChapter = section
+	bc.headingCounts = [7]int{}
+	bc.ensureChapterBreak(section.title)
+	bc.renderTitle(section.title)
+
+	bc.currentFile = section.path
+	if err := bc.processMarkdownFile(section.path); err != nil {
+		return fmt.Errorf("failed to process file %s: %w", section.path, err)
+	}
+	bc.reportProgress(section.path)
+
+	bc.pdf.Ln(defaultLineHeight * 2)
+	return nil
+}
+
 // renderChapterTitle adds a formatted chapter title to the PDF.
 //
 // Parameters:
-//   - chapterPath: Path containing the chapter name to format
+//   - chapter: Chapter whose title is rendered
+//   - subtitle: Line drawn below the title; empty for none
 //
 // Returns:
 //   - error: Any rendering errors encountered
@@ -219,21 +790,181 @@ func (bc *BookCompiler) processChapter(chapter Chapter) error {
 // - Consistent font styling
 // - Proper vertical spacing
 // - Episode number extraction
-func (bc *BookCompiler) renderChapterTitle(chapterPath string) error {
-	title := formatChapterTitle(chapterPath)
+//
+// The title is placed, ruled, and labeled as configured with
+// SetChapterTitleLayout.
+func (bc *BookCompiler) renderChapterTitle(chapter Chapter, subtitle string) error {
+	label := ""
+	if bc.chapterLabel != "" {
+		number := strconv.Itoa(extractEpisodeNumber(chapter.Path))
+		label = strings.ReplaceAll(bc.chapterLabel, "{n}", number)
+	}
 
-	bc.pdf.SetFont(bc.chapterFont, chapterTitleFont, chapterTitleSize)
+	height := chapterLineHeight
+	if label != "" {
+		height += chapterLabelHeight
+	}
+	if subtitle != "" {
+		height += chapterSubtitleHeight
+	}
+	author := chapter.Meta[authorField]
+	if author != "" {
+		height += chapterAuthorHeight
+	}
+	_, top, _, _ := bc.pdf.GetMargins()
+	_, bottom := bc.pdf.GetAutoPageBreak()
+	space := bc.getPageHeight() - top - bottom - height
+	switch bc.chapterTitlePosition {
+	case ChapterTitleUpperThird:
+		bc.pdf.SetY(top + space/3)
+	case ChapterTitleCentered:
+		bc.pdf.SetY(top + space/2)
+	}
 
-	// Center title horizontally
-	titleWidth := bc.pdf.GetStringWidth(title)
-	pageWidth, _, _ := bc.pdf.PageSize(0)
-	x := (pageWidth - titleWidth) / 2
+	if label != "" {
+		bc.drawTitleLine(label, fontStyleNormal, chapterLabelSize, chapterLabelHeight, "P")
+		bc.pdf.Ln(chapterLabelHeight)
+	}
+	bc.drawTitleLine(chapterTitle(chapter), chapterTitleFont, chapterTitleSize, chapterLineHeight, "H1")
+	lastHeight := chapterLineHeight
+	if subtitle != "" {
+		bc.pdf.Ln(chapterLineHeight)
+		bc.drawTitleLine(subtitle, fontStyleNormal, chapterSubtitleSize, chapterSubtitleHeight, "P")
+		lastHeight = chapterSubtitleHeight
+	}
+	if author != "" {
+		bc.pdf.Ln(lastHeight)
+		bc.drawTitleLine(author, fontStyleNormal, chapterAuthorSize, chapterAuthorHeight, "P")
+		lastHeight = chapterAuthorHeight
+	}
+	if bc.chapterTitleRule {
+		bc.drawChapterRule(bc.pdf.GetY() + lastHeight + chapterRuleOffset)
+	}
+	bc.pdf.Ln(chapterSpacing)
+	return nil
+}
 
-	bc.pdf.SetX(x)
-	bc.pdf.Cell(titleWidth, chapterLineHeight, title)
+// drawChapterRule draws a short centered rule in the heading color below
+// a chapter title.
+//
+// Parameters:
+//   - y: Vertical position of the rule
+func (bc *BookCompiler) drawChapterRule(y float64) {
+	pageWidth, _ := bc.pdf.GetPageSize()
+	left, _, right, _ := bc.pdf.GetMargins()
+	width := (pageWidth - left - right) * chapterRuleRatio
+
+	drawR, drawG, drawB := bc.pdf.GetDrawColor()
+	lineWidth := bc.pdf.GetLineWidth()
+	bc.pdf.SetDrawColor(bc.headingColor.r, bc.headingColor.g, bc.headingColor.b)
+	bc.pdf.SetLineWidth(chapterRuleWeight)
+	bc.pdf.Line((pageWidth-width)/2, y, (pageWidth+width)/2, y)
+	bc.pdf.SetDrawColor(drawR, drawG, drawB)
+	bc.pdf.SetLineWidth(lineWidth)
+}
+
+// renderTitle writes a centered chapter or section title followed by
+// chapter spacing. Pair kerning is applied when enabled.
+//
+// Parameters:
+//   - title: Title text
+func (bc *BookCompiler) renderTitle(title string) {
+	bc.drawTitleLine(title, chapterTitleFont, chapterTitleSize, chapterLineHeight, "H1")
 	bc.pdf.Ln(chapterSpacing)
+}
 
-	return nil
+// drawTitleLine writes one line of a title, centered on the page in the
+// chapter font and heading color, without moving to the next line. Pair
+// kerning is applied when enabled.
+//
+// Parameters:
+//   - text: Text of the line
+//   - style: Font style
+//   - size: Font size in points
+//   - height: Line height in millimeters
+//   - tag: Structure type of the line in tagged PDFs
+func (bc *BookCompiler) drawTitleLine(text, style string, size, height float64, tag string) {
+	text = bc.normalizeText(text)
+	previousColor := bc.setTextColor(bc.headingColor)
+	defer bc.restoreTextColor(previousColor)
+	bc.setFont(bc.chapterFont, style, size)
+
+	// Center the line horizontally
+	width := bc.pdf.GetStringWidth(text)
+	if bc.kerning {
+		width = bc.kernedStringWidth(text)
+	}
+	pageWidth, _, _ := bc.pdf.PageSize(0)
+	x := (pageWidth - width) / 2
+
+	page := bc.beginMarkedContent(tag, "")
+	if !isCoreText(text) {
+		// Symbols are drawn in their own font, without kerning
+		bc.pdf.SetX(0)
+		bc.cellWithFallback(pageWidth, height, text, 0, AlignCenter, 0)
+	} else if bc.kerning {
+		bc.writeKernedCell(x, height, text)
+	} else {
+		bc.pdf.SetX(x)
+		bc.pdf.Cell(width, height, text)
+	}
+	bc.endMarkedContent(page)
+}
+
+// leadingHeading returns the text of the H1 a markdown file opens with,
+// shown as the chapter subtitle.
+//
+// Parameters:
+//   - file: Chapter's first markdown file
+//
+// Returns:
+//   - string: Heading text, or empty if the file does not start with an
+//     H1 or cannot be read
+func (bc *BookCompiler) leadingHeading(file string) string {
+	content, err := bc.readFile(file)
+	if err != nil {
+		return ""
+	}
+	_, content = splitFrontMatter(content)
+	doc, err := html.Parse(bytes.NewReader(bc.markdownToHTML(content)))
+	if err != nil {
+		return ""
+	}
+	body := findBodyNode(doc)
+	if body == nil {
+		return ""
+	}
+	first := firstElementChild(body)
+	if first == nil || first.Data != "h1" {
+		return ""
+	}
+	return strings.TrimSpace(getTextContent(first))
+}
+
+// removeLeadingHeading removes the H1 shown as the chapter subtitle from
+// the start of a chapter's first file, registering its id as a link
+// destination on the chapter opener.
+//
+// Parameters:
+//   - body: Parsed content of the file
+func (bc *BookCompiler) removeLeadingHeading(body *html.Node) {
+	first := firstElementChild(body)
+	if first == nil || first.Data != "h1" {
+		return
+	}
+	if id := getAttr(first, "id"); id != "" {
+		bc.registerAnchor(id)
+	}
+	body.RemoveChild(first)
+}
+
+// chapterTitle returns the display title of a chapter: its Title if set,
+// otherwise the title formatted from its path.
+func chapterTitle(chapter Chapter) string {
+	if chapter.Title != "" {
+		return chapter.Title
+	}
+	return formatChapterTitle(chapter.Path)
 }
 
 // formatChapterTitle creates a consistent chapter title from the path.
@@ -254,6 +985,41 @@ func formatChapterTitle(path string) string {
 	return fmt.Sprintf("Episode %s", strings.TrimSpace(base))
 }
 
+// numberCaption prefixes a caption with the next figure or table label.
+//
+// Parameters:
+//   - prefix: figureLabelPrefix or tableLabelPrefix
+//   - id: Optional element id registered as a cross-reference target
+//   - caption: Caption text, may be empty
+//
+// Returns:
+//   - string: Numbered caption (e.g., "Figure 3: Overview"), or the caption
+//     unchanged when numbering is disabled
+func (bc *BookCompiler) numberCaption(prefix, id, caption string) string {
+	if bc.figureNumbering == NumberingNone {
+		return caption
+	}
+
+	counter := &bc.figureCount
+	if prefix == tableLabelPrefix {
+		counter = &bc.tableCount
+	}
+	*counter++
+
+	label := fmt.Sprintf("%s %d", prefix, *counter)
+	if bc.figureNumbering == NumberingPerChapter {
+		label = fmt.Sprintf("%s %d.%d", prefix, bc.chapterNumber, *counter)
+	}
+	if id != "" {
+		bc.captionLabels[id] = label
+	}
+
+	if caption == "" {
+		return label
+	}
+	return label + ": " + caption
+}
+
 // processMarkdownFile converts a single markdown file to PDF content.
 //
 // Parameters:
@@ -263,7 +1029,7 @@ func formatChapterTitle(path string) string {
 //   - error: File processing errors
 //
 // Process:
-// 1. Read markdown file
+// 1. Read markdown file and strip front matter
 // 2. Convert to HTML
 // 3. Parse HTML structure
 // 4. Render content
@@ -274,14 +1040,51 @@ func formatChapterTitle(path string) string {
 // - Missing body element
 // - Rendering errors
 func (bc *BookCompiler) processMarkdownFile(filePath string) error {
-	content, err := ioutil.ReadFile(filePath)
+	content, err := bc.readFile(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
+	_, content = splitFrontMatter(content)
+	return bc.renderMarkdown(content)
+}
 
-	htmlContent := convertMarkdownToHTML(content)
+// RenderMarkdown renders a markdown snippet at the current position with
+// the same conversion and rendering as chapter files, for inserting
+// generated content such as a statistics page. Call it while a document
+// is being compiled, for example from a progress callback; called before
+// Compile, it starts a document of its own on a new page, which lets the
+// renderer be exercised in isolation but is discarded by Compile.
+//
+// Parameters:
+//   - md: Markdown content; front matter is not stripped
+//
+// Returns:
+//   - error: HTML parsing or rendering errors
+func (bc *BookCompiler) RenderMarkdown(md []byte) error {
+	if bc.pdf == nil {
+		bc.initializePDF()
+		bc.pdf.AddPage()
+		bc.setFont(bc.textFont, fontStyleNormal, defaultFontSize)
+	}
+	return bc.renderMarkdown(md)
+}
 
+// renderMarkdown converts markdown to HTML and renders it at the current
+// position.
+//
+// Parameters:
+//   - content: Markdown content without front matter
+//
+// Returns:
+//   - error: HTML parsing or rendering errors
+func (bc *BookCompiler) renderMarkdown(content []byte) error {
+	start := time.Now()
+	htmlContent := bc.markdownToHTML(content)
+	bc.trackPhase(&bc.timings.Conversion, start)
+
+	start = time.Now()
 	doc, err := html.Parse(bytes.NewReader(htmlContent))
+	bc.trackPhase(&bc.timings.Parsing, start)
 	if err != nil {
 		return fmt.Errorf("failed to parse HTML: %w", err)
 	}
@@ -290,7 +1093,13 @@ func (bc *BookCompiler) processMarkdownFile(filePath string) error {
 	if body == nil {
 		return ErrNoBody
 	}
+	prepareContent(body)
+	if bc.skipLeadingHeading {
+		bc.skipLeadingHeading = false
+		bc.removeLeadingHeading(body)
+	}
 
+	defer bc.trackPhase(&bc.timings.Rendering, time.Now())
 	if err := bc.renderChildren(body); err != nil {
 		return fmt.Errorf("failed to render content: %w", err)
 	}
@@ -298,23 +1107,82 @@ func (bc *BookCompiler) processMarkdownFile(filePath string) error {
 	return nil
 }
 
+// ConvertFileToHTML returns the HTML the compiler renders for a markdown
+// file: its content after the front matter block, converted with the
+// configured markdown extensions. Use it to debug rendering issues or to
+// test the HTML stage independently of PDF output.
+//
+// Parameters:
+//   - path: Markdown file path, resolved like chapter files
+//
+// Returns:
+//   - []byte: HTML fragment
+//   - error: File reading errors
+func (bc *BookCompiler) ConvertFileToHTML(path string) ([]byte, error) {
+	content, err := bc.readFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	_, content = splitFrontMatter(content)
+	return bc.markdownToHTML(content), nil
+}
+
+// MarkdownToHTML converts markdown to HTML with the default markdown
+// extensions and without smart punctuation, as NewBookCompiler configures
+// them. Front matter is not stripped.
+//
+// Parameters:
+//   - content: Raw markdown bytes
+//
+// Returns:
+//   - []byte: HTML fragment
+func MarkdownToHTML(content []byte) []byte {
+	return convertMarkdownToHTML(content, defaultMarkdownExtensions, defaultHTMLFlags)
+}
+
 // convertMarkdownToHTML transforms markdown content to HTML format.
 //
 // Parameters:
 //   - content: Raw markdown bytes
+//   - extensions: blackfriday extension flags to enable
+//   - flags: blackfriday HTML renderer flags
 //
 // Returns:
 //   - []byte: HTML content bytes
 //
 // Features:
-// - Common markdown extensions enabled
+// - Configurable markdown extensions (CommonExtensions by default)
 // - GitHub-flavored markdown support
 // - Preserves formatting and structure
 //
-// Uses blackfriday markdown parser with standard extensions.
-func convertMarkdownToHTML(content []byte) []byte {
-	return blackfriday.Run(content,
-		blackfriday.WithExtensions(blackfriday.CommonExtensions))
+// Uses blackfriday markdown parser.
+func convertMarkdownToHTML(content []byte, extensions blackfriday.Extensions, flags blackfriday.HTMLFlags) []byte {
+	renderer := blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{Flags: flags})
+	return blackfriday.Run(rewriteAdmonitionFences(content),
+		blackfriday.WithRenderer(renderer), blackfriday.WithExtensions(extensions))
+}
+
+// markdownToHTML converts markdown with the configured extensions and
+// smart punctuation setting, marking its math first when math is enabled.
+func (bc *BookCompiler) markdownToHTML(content []byte) []byte {
+	if bc.math {
+		content = markMath(content)
+	}
+	flags := defaultHTMLFlags
+	if bc.smartPunctuation {
+		flags |= smartPunctuationFlags
+	}
+	return convertMarkdownToHTML(content, bc.markdownExtensions, flags)
+}
+
+// prepareContent adjusts parsed markdown for rendering: admonition fences
+// are grouped into callouts and escaped numeric entities are decoded.
+//
+// Parameters:
+//   - n: Element holding the converted markdown
+func prepareContent(n *html.Node) {
+	groupAdmonitions(n)
+	decodeEntities(n)
 }
 
 // findBodyNode locates the body element in an HTML document.
@@ -0,0 +1,239 @@
+package bookie
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeJPEG writes a small solid-color JPEG image.
+func writeJPEG(t *testing.T, path string) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 40, 60))
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 60; y++ {
+			img.Set(x, y, color.RGBA{200, 80, 40, 255})
+		}
+	}
+	var data bytes.Buffer
+	if err := jpeg.Encode(&data, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChapterCoverPrecedesText(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "book")
+	writeJPEG(t, filepath.Join(dir, "shared", "cover.jpg"))
+	if err := os.MkdirAll(filepath.Join(root, "Episode01"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// The cover path resolves only relative to the chapter's own file
+	content := "---\ncover: ../../shared/cover.jpg\n---\nThe chapter begins.\n"
+	if err := os.WriteFile(filepath.Join(root, "Episode01", "content.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, pdf := compileRecorded(t, root, nil)
+
+	images := pdf.methodCalls("Image")
+	if len(images) != 1 {
+		t.Fatalf("drew %d images, want the cover", len(images))
+	}
+	cover := images[0]
+	pageWidth, pageHeight := pdf.GetPageSize()
+	if cover.w < pageWidth-2*pdfMargin-0.01 && cover.h < pageHeight-2*pdfMargin-0.01 {
+		t.Errorf("cover is %.1fx%.1fmm, want it to fill the content area", cover.w, cover.h)
+	}
+	if page := pdf.pageOf("Episode 01"); page != cover.page+1 {
+		t.Errorf("chapter title on page %d, want %d after the cover", page, cover.page+1)
+	}
+	if page := pdf.pageOf("The chapter begins."); page != cover.page+1 {
+		t.Errorf("chapter text on page %d, want %d after the cover", page, cover.page+1)
+	}
+}
+
+func TestCompileSplitReportsUnresolvedImages(t *testing.T) {
+	root := writeBook(t, map[string]string{
+		"Episode01/content.md": "---\ncover: missing-cover.jpg\n---\nFirst chapter.\n",
+		"Episode02/content.md": "Second chapter.\n\n![Map](missing-map.jpg)\n",
+	})
+	bc, _ := newRecordedCompiler(t, root)
+	bc.SetContinueOnError(true)
+	outDir := filepath.Join(t.TempDir(), "split")
+
+	err := bc.CompileSplit(outDir)
+	if !errors.Is(err, ErrUnresolvedImages) {
+		t.Fatalf("CompileSplit error = %v, want ErrUnresolvedImages", err)
+	}
+	for _, src := range []string{"missing-cover.jpg", "missing-map.jpg"} {
+		if !strings.Contains(err.Error(), src) {
+			t.Errorf("error %q does not list %s", err, src)
+		}
+	}
+	if got := bc.UnresolvedImages(); len(got) != 2 {
+		t.Errorf("UnresolvedImages = %q, want both chapters' images", got)
+	}
+	for _, name := range []string{"Episode01.pdf", "Episode02.pdf"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("%s not written: %v", name, err)
+		}
+	}
+}
+
+func TestMissingCoverContinuesOnError(t *testing.T) {
+	root := writeBook(t, map[string]string{
+		"Episode01/content.md": "---\ncover: missing-cover.jpg\n---\nThe chapter begins.\n",
+	})
+	bc, recording := newRecordedCompiler(t, root)
+	bc.SetContinueOnError(true)
+
+	if err := bc.Compile(); !errors.Is(err, ErrUnresolvedImages) {
+		t.Fatalf("Compile error = %v, want ErrUnresolvedImages", err)
+	}
+	pdf := recording()
+	placeholder := pdf.pageOf("Missing image: missing-cover.jpg")
+	if placeholder == 0 {
+		t.Fatal("no placeholder drawn for the missing cover")
+	}
+	if page := pdf.pageOf("The chapter begins."); page != placeholder+1 {
+		t.Errorf("chapter text on page %d, want %d after the cover placeholder", page, placeholder+1)
+	}
+}
+
+// longChapter is chapter content that runs over several pages.
+var longChapter = strings.Repeat("A paragraph of body text that fills the page line by line.\n\n", 80)
+
+// headerRules returns the lines drawn at the header rule position.
+func headerRules(pdf *recordingPDF) []pdfCall {
+	var rules []pdfCall
+	for _, line := range pdf.methodCalls("Line") {
+		if line.y == pdfMargin-headerRuleOffset && line.h == line.y {
+			rules = append(rules, line)
+		}
+	}
+	return rules
+}
+
+func TestHeaderRule(t *testing.T) {
+	root := writeBook(t, map[string]string{"Episode01/content.md": longChapter})
+
+	_, pdf := compileRecorded(t, root, func(bc *BookCompiler) {
+		bc.SetHeaderRule(true, 0.8, 200, 0, 0)
+	})
+	rules := headerRules(pdf)
+	if len(rules) == 0 {
+		t.Fatal("no header rule drawn with the rule enabled")
+	}
+	for _, rule := range rules {
+		if rule.lineWidth != 0.8 || rule.drawColor != [3]int{200, 0, 0} {
+			t.Errorf("header rule on page %d drawn %.2fmm wide in %v, want 0.80mm in [200 0 0]",
+				rule.page, rule.lineWidth, rule.drawColor)
+		}
+	}
+
+	_, pdf = compileRecorded(t, root, func(bc *BookCompiler) {
+		bc.SetHeaderRule(false, 0.8, 200, 0, 0)
+	})
+	if rules := headerRules(pdf); len(rules) > 0 {
+		t.Errorf("%d header rules drawn with the rule disabled", len(rules))
+	}
+}
+
+func TestContinuationNoteInHeader(t *testing.T) {
+	root := writeBook(t, map[string]string{"Episode01/content.md": longChapter})
+	bc, pdf := compileRecorded(t, root, func(bc *BookCompiler) {
+		bc.SetRunningHeaders(true)
+		bc.SetHeaderTemplates("{chapter}", "{chapter}")
+		bc.SetContinuationNote(true)
+	})
+
+	opening := bc.chapterStartPage
+	if text := pdf.pageText(opening); strings.Contains(text, continuationSuffix) {
+		t.Errorf("chapter opening page %d has %q, want no continuation note", opening, text)
+	}
+	if text := pdf.pageText(opening + 1); !strings.Contains(text, "Episode 01"+continuationSuffix) {
+		t.Errorf("header on the chapter's second page %d is missing %q: %q", opening+1, continuationSuffix, text)
+	}
+}
+
+func TestCompileSplitOnePDFPerChapter(t *testing.T) {
+	root := writeBook(t, map[string]string{
+		"Episode01/content.md": "Text of the first chapter.\n",
+		"Episode02/content.md": "Text of the second chapter.\n",
+	})
+	bc := NewBookCompiler(root, filepath.Join(t.TempDir(), "book.pdf"))
+	var documents []*recordingPDF
+	bc.newPDF = func() pdfWriter {
+		documents = append(documents, newRecordingPDF())
+		return documents[len(documents)-1]
+	}
+	outDir := filepath.Join(t.TempDir(), "split")
+
+	if err := bc.CompileSplit(outDir); err != nil {
+		t.Fatalf("CompileSplit: %v", err)
+	}
+
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "Episode01.pdf,Episode02.pdf" {
+		t.Errorf("CompileSplit wrote %q, want one PDF per chapter", names)
+	}
+
+	if len(documents) != 2 {
+		t.Fatalf("created %d documents, want 2", len(documents))
+	}
+	for i, want := range []string{"first", "second"} {
+		other := []string{"second", "first"}[i]
+		pdf := documents[i]
+		if pdf.pageOf("Text of the "+want) == 0 {
+			t.Errorf("document %d lacks its chapter's text", i+1)
+		}
+		if pdf.pageOf("Text of the "+other) != 0 {
+			t.Errorf("document %d contains the %s chapter's text", i+1, other)
+		}
+	}
+}
+
+func TestPagesBetweenChapters(t *testing.T) {
+	root := writeBook(t, map[string]string{
+		"Episode01/content.md": "First chapter body.\n\n<!-- pagebreak -->\n",
+		"Episode02/content.md": "Second chapter body.\n",
+		"Episode03/content.md": "Third chapter body.\n",
+	})
+	_, pdf := compileRecorded(t, root, nil)
+
+	// Contents on page 1; the first chapter on page 2, where its trailing
+	// page break is dropped; the second on page 3, an odd page; and the
+	// third on page 5, after one filler page.
+	for body, want := range map[string]int{
+		"First chapter body":  2,
+		"Second chapter body": 3,
+		"Third chapter body":  5,
+	} {
+		if page := pdf.pageOf(body); page != want {
+			t.Errorf("%q on page %d, want %d", body, page, want)
+		}
+	}
+	if pages := len(pdf.methodCalls("AddPage")); pages != 5 {
+		t.Errorf("document has %d pages, want 5", pages)
+	}
+}
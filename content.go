@@ -0,0 +1,47 @@
+package bookie
+
+import (
+	"fmt"
+	"path"
+)
+
+// contentRoot is the virtual directory holding in-memory chapters and
+// images.
+const contentRoot = "memory"
+
+// AddChapterContent appends a chapter supplied as markdown, e.g. from a
+// database or an upload, instead of a directory on disk. Once any chapter
+// has been added this way, Compile uses only these chapters, in the order
+// added, and does not scan RootDir.
+//
+// Parameters:
+//   - title: Chapter title shown on its opening page and in the ToC
+//   - markdown: Chapter content, optionally starting with front matter
+//
+// Images referenced by the markdown are supplied with AddImageContent.
+func (bc *BookCompiler) AddChapterContent(title string, markdown []byte) {
+	dir := path.Join(contentRoot, fmt.Sprintf("%s%02d", episodePrefix, len(bc.contentChapters)+1))
+	file := path.Join(dir, "content"+markdownExt)
+	bc.contents[file] = markdown
+
+	meta, _ := splitFrontMatter(markdown)
+	bc.contentChapters = append(bc.contentChapters, Chapter{
+		Path:   dir,
+		Title:  title,
+		Files:  []string{file},
+		Images: bc.contentImages,
+		Meta:   meta,
+	})
+}
+
+// AddImageContent supplies the data of an image referenced by chapters
+// added with AddChapterContent.
+//
+// Parameters:
+//   - name: Image file name as referenced in the markdown (e.g., "map.png")
+//   - data: Encoded JPEG, PNG, or GIF image
+func (bc *BookCompiler) AddImageContent(name string, data []byte) {
+	file := path.Join(contentRoot, "images", name)
+	bc.contents[file] = data
+	bc.contentImages[name] = file
+}
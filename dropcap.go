@@ -0,0 +1,92 @@
+package bookie
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Drop cap layout parameters.
+const (
+	dropCapLines       = 3    // Body lines spanned by the drop cap
+	dropCapGap         = 1.5  // Space between the drop cap and the text
+	dropCapHeightRatio = 0.66 // Cap height of the core fonts relative to the font size
+)
+
+// startDropCap draws the first letter of a paragraph as a drop cap and
+// narrows the left margin so the following lines wrap beside it.
+//
+// Parameters:
+//   - n: Paragraph element about to be rendered
+//
+// The letter is removed from the paragraph's first text node. Paragraphs
+// that do not start with a letter or digit are left unchanged. Call
+// endDropCap after the paragraph has been rendered.
+func (bc *BookCompiler) startDropCap(n *html.Node) {
+	text := firstTextNode(n)
+	if text == nil {
+		return
+	}
+	trimmed := strings.TrimLeftFunc(text.Data, unicode.IsSpace)
+	letter, size := utf8.DecodeRuneInString(trimmed)
+	if !(unicode.IsLetter(letter) || unicode.IsDigit(letter)) || !hasCoreGlyph(letter) {
+		return
+	}
+	text.Data = trimmed[size:]
+
+	k := bc.pdf.GetConversionRatio()
+	lineHeight := bc.lineHeight()
+	bodySize := bc.font.Size / k
+	capSize := float64(dropCapLines-1)*lineHeight/dropCapHeightRatio + bodySize
+
+	left, _, _, _ := bc.pdf.GetMargins()
+	x, y := left, bc.pdf.GetY()
+	baseline := y + float64(dropCapLines-1)*lineHeight + 0.5*lineHeight + 0.3*bodySize
+
+	previous := bc.font
+	bc.pdf.SetFont(previous.FontFamily, previous.Style, capSize*k)
+	bc.pdf.Text(x, baseline, string(letter))
+	width := bc.pdf.GetStringWidth(string(letter))
+	bc.restoreTextState(previous)
+
+	bc.dropCapMargin = left
+	bc.dropCapBottom = y + float64(dropCapLines)*lineHeight
+	bc.pdf.SetLeftMargin(x + width + dropCapGap)
+	bc.pdf.SetX(x + width + dropCapGap)
+}
+
+// endDropCap restores the left margin after a drop cap paragraph's text.
+// If the paragraph was shorter than the drop cap, the position moves to
+// its last line so the paragraph's closing line break clears it.
+func (bc *BookCompiler) endDropCap() {
+	if bc.dropCapBottom == 0 {
+		return
+	}
+	bc.pdf.SetLeftMargin(bc.dropCapMargin)
+	if last := bc.dropCapBottom - bc.lineHeight(); bc.pdf.GetY() < last {
+		bc.pdf.SetY(last)
+	}
+	bc.dropCapBottom = 0
+}
+
+// firstTextNode returns the first descendant text node with visible
+// content.
+//
+// Parameters:
+//   - n: Node whose subtree is searched
+//
+// Returns:
+//   - *html.Node: The text node, or nil if there is none
+func firstTextNode(n *html.Node) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode && strings.TrimSpace(c.Data) != "" {
+			return c
+		}
+		if found := firstTextNode(c); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -2,11 +2,25 @@ package bookie
 
 import (
 	"errors"
+	"fmt"
 	"os"
 )
 
+// tempDir is the directory for DirectoryToPDF's intermediate files; empty
+// means the system default (os.TempDir).
+var tempDir string
+
+// SetTempDir sets the directory DirectoryToPDF uses for its intermediate
+// PDF file, for sandboxed environments where the system temp directory is
+// unavailable. An empty dir restores the system default. It should be
+// called before conversions start, not concurrently with them.
+func SetTempDir(dir string) {
+	tempDir = dir
+}
+
 // DirectoryToPDF converts a directory containing markdown files into a PDF byte slice.
 // The directory should follow the Bookie chapter structure (Episode01, Episode02, etc.).
+// The PDF is staged in a temporary file in the directory set by SetTempDir.
 //
 // Parameters:
 //   - dirPath: Path to the directory containing markdown files organized in chapters
@@ -28,13 +42,16 @@ func DirectoryToPDF(dirPath string) ([]byte, error) {
 		return nil, errors.New("directory path cannot be empty")
 	}
 
-	// Create a temporary file for the PDF output
-	tmpFile, err := os.CreateTemp("", "bookie-*.pdf")
+	// Create a temporary file for the PDF output; it is closed at once
+	// so the compiler can write it, and removed on every return path
+	tmpFile, err := os.CreateTemp(tempDir, "bookie-*.pdf")
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if err := tmpFile.Close(); err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
 	}
-	defer os.Remove(tmpFile.Name()) // Clean up temporary file
-	defer tmpFile.Close()
 
 	// Create a new book compiler
 	compiler := NewBookCompiler(dirPath, tmpFile.Name())
@@ -0,0 +1,60 @@
+package bookie
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// fenceLanguagePrefix starts the class blackfriday gives the code element
+// of a fenced code block tagged with a language ("language-mermaid").
+const fenceLanguagePrefix = "language-"
+
+// fenceRenderer finds the renderer registered for a code block's
+// language.
+//
+// Parameters:
+//   - n: pre or code element
+//
+// Returns:
+//   - string: The block's language
+//   - FenceRenderer: The renderer registered for it
+//   - bool: false if n is not a code block in a registered language
+func (bc *BookCompiler) fenceRenderer(n *html.Node) (string, FenceRenderer, bool) {
+	code := n
+	if n.Data == "pre" {
+		code = firstElementChild(n)
+	}
+	if code == nil || code.Data != "code" {
+		return "", nil, false
+	}
+
+	for _, class := range strings.Fields(getAttr(code, "class")) {
+		lang := strings.TrimPrefix(class, fenceLanguagePrefix)
+		if lang == class {
+			continue
+		}
+		if fn, ok := bc.fenceRenderers[lang]; ok {
+			return lang, fn, true
+		}
+	}
+	return "", nil, false
+}
+
+// renderFence embeds the image a fence renderer makes from a code block.
+//
+// Parameters:
+//   - n: pre or code element
+//   - lang: The block's language
+//   - fn: Renderer registered for the language
+//
+// Returns:
+//   - error: Renderer or image loading errors
+func (bc *BookCompiler) renderFence(n *html.Node, lang string, fn FenceRenderer) error {
+	path, err := bc.renderedImage(lang, getTextContent(n), fn)
+	if err != nil {
+		return fmt.Errorf("failed to render %s block: %w", lang, err)
+	}
+	return bc.handleImage(path, "", lang+" diagram")
+}
@@ -0,0 +1,67 @@
+package bookie
+
+import (
+	"bytes"
+	"strings"
+)
+
+// frontMatterDelimiter opens and closes a front matter block at the very
+// start of a markdown file.
+const frontMatterDelimiter = "---"
+
+// splitFrontMatter separates a leading front matter block from markdown
+// content. The block starts with a "---" line on the first line of the file
+// and ends at the next "---" line; each line within it is a "key: value"
+// pair. Keys are lowercased and values are trimmed of whitespace and quotes.
+//
+// Parameters:
+//   - content: Raw markdown file content
+//
+// Returns:
+//   - map[string]string: Front matter fields, nil if there is no block
+//   - []byte: Markdown content following the block, or the original
+//     content if there is no block
+func splitFrontMatter(content []byte) (map[string]string, []byte) {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if len(lines) == 0 || strings.TrimSpace(string(lines[0])) != frontMatterDelimiter {
+		return nil, content
+	}
+
+	fields := make(map[string]string)
+	offset := len(lines[0])
+	for _, line := range lines[1:] {
+		offset += len(line)
+		text := strings.TrimSpace(string(line))
+		if text == frontMatterDelimiter {
+			return fields, content[offset:]
+		}
+
+		key, value, ok := strings.Cut(text, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		fields[key] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+
+	// Unterminated block: treat the whole file as markdown
+	return nil, content
+}
+
+// readFrontMatter returns the front matter fields of a markdown file.
+//
+// Parameters:
+//   - path: Markdown file path
+//
+// Returns:
+//   - map[string]string: Front matter fields, nil if the file has none
+//   - error: File reading errors
+func (bc *BookCompiler) readFrontMatter(path string) (map[string]string, error) {
+	content, err := bc.readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, _ := splitFrontMatter(content)
+	return fields, nil
+}
@@ -0,0 +1,168 @@
+package bookie
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// NewBookCompilerFromFS creates a BookCompiler that reads the book from
+// fsys instead of the operating system's filesystem, so books embedded
+// with go:embed or served from a virtual filesystem can be compiled.
+//
+// Parameters:
+//   - fsys: Filesystem holding the book
+//   - root: Directory within fsys containing the episode folders
+//   - outputPath: PDF file written by Compile, on the real filesystem
+//
+// Returns:
+//   - *BookCompiler: Compiler with the default settings
+//
+// Chapter discovery, markdown files (including front and back matter
+// added with paths), and the images they reference are read from fsys;
+// paths are slash-separated and relative to its root. Cover, watermark,
+// and font files are read from the operating system.
+func NewBookCompilerFromFS(fsys fs.FS, root, outputPath string) *BookCompiler {
+	bc := NewBookCompiler(root, outputPath)
+	bc.fsys = fsys
+	return bc
+}
+
+// fsPath converts a path to the slash-separated, unrooted form fs.FS
+// expects.
+//
+// Parameters:
+//   - name: Path as built with filepath
+//
+// Returns:
+//   - string: Cleaned path relative to the root of the filesystem
+func fsPath(name string) string {
+	return strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+}
+
+// readFile reads a book file from in-memory content or the compiler's
+// filesystem.
+func (bc *BookCompiler) readFile(name string) ([]byte, error) {
+	if data, ok := bc.contents[name]; ok {
+		return data, nil
+	}
+	if bc.fsys == nil {
+		return os.ReadFile(name)
+	}
+	return fs.ReadFile(bc.fsys, fsPath(name))
+}
+
+// readDir lists a book directory from the compiler's filesystem.
+func (bc *BookCompiler) readDir(name string) ([]fs.DirEntry, error) {
+	if bc.fsys == nil {
+		return os.ReadDir(name)
+	}
+	return fs.ReadDir(bc.fsys, fsPath(name))
+}
+
+// stat describes a book file from the compiler's filesystem.
+func (bc *BookCompiler) stat(name string) (fs.FileInfo, error) {
+	if bc.fsys == nil {
+		return os.Stat(name)
+	}
+	return fs.Stat(bc.fsys, fsPath(name))
+}
+
+// walkDir walks a book directory tree in the compiler's filesystem.
+func (bc *BookCompiler) walkDir(root string, fn fs.WalkDirFunc) error {
+	if bc.fsys == nil {
+		return filepath.WalkDir(root, fn)
+	}
+	return fs.WalkDir(bc.fsys, fsPath(root), fn)
+}
+
+// registerImage loads a book image into the PDF. Images are read from
+// in-memory content or the compiler's filesystem, except downloaded remote
+// images, which are always temporary files on disk. Images are
+// downsampled first when a downsampling resolution is set.
+//
+// Parameters:
+//   - name: Image path as returned by resolveImagePath
+//   - width: Width the image is displayed at, in millimeters
+//
+// Returns:
+//   - *gofpdf.ImageInfoType: Image information, or nil if loading failed
+func (bc *BookCompiler) registerImage(name string, width float64) *gofpdf.ImageInfoType {
+	_, inMemory := bc.contents[name]
+	onDisk := !inMemory && (bc.fsys == nil || bc.isDownloadedImage(name))
+	if onDisk && bc.imageDPI == 0 {
+		return bc.pdf.RegisterImage(name, "")
+	}
+
+	var data []byte
+	var err error
+	if onDisk {
+		data, err = os.ReadFile(name)
+	} else {
+		data, err = bc.readFile(name)
+	}
+	if err != nil {
+		bc.pdf.SetError(err)
+		return nil
+	}
+	if bc.imageDPI > 0 {
+		data = bc.downsampleImage(name, data, width)
+	}
+	options := gofpdf.ImageOptions{ImageType: strings.TrimPrefix(filepath.Ext(name), ".")}
+	return bc.pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(data))
+}
+
+// renderedImage returns the image a math or fence renderer makes from
+// source, invoking the renderer once per distinct source. The image is
+// loaded into memory so that it is found even when the book is read from
+// an fs.FS.
+//
+// Parameters:
+//   - kind: Renderer name, keeping the caches of renderers apart
+//   - source: Text to render
+//   - render: Renderer returning the path of a JPEG image
+//
+// Returns:
+//   - string: Image path
+//   - error: Renderer, image format, or file reading errors
+func (bc *BookCompiler) renderedImage(kind, source string, render func(string) (string, error)) (string, error) {
+	key := kind + "\x00" + source
+	if path, ok := bc.renderedImages[key]; ok {
+		return path, nil
+	}
+
+	path, err := render(source)
+	if err != nil {
+		return "", err
+	}
+	if !isJPEGImage(path) {
+		return "", fmt.Errorf("unsupported image format: %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read rendered image: %w", err)
+	}
+
+	bc.contents[path] = data
+	if bc.renderedImages == nil {
+		bc.renderedImages = make(map[string]string)
+	}
+	bc.renderedImages[key] = path
+	return path, nil
+}
+
+// isDownloadedImage reports whether a path is a downloaded remote image.
+func (bc *BookCompiler) isDownloadedImage(name string) bool {
+	for _, file := range bc.remoteImages {
+		if file == name {
+			return true
+		}
+	}
+	return false
+}
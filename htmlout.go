@@ -0,0 +1,80 @@
+package bookie
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// defaultHTMLTitle is the document title used when no book title is set.
+const defaultHTMLTitle = "Book"
+
+// htmlStylesheet mirrors the PDF heading hierarchy and body font so the web
+// output reads like the compiled book.
+const htmlStylesheet = `body { max-width: 42em; margin: 2em auto; padding: 0 1em; font-family: Helvetica, Arial, sans-serif; font-size: 12pt; line-height: 1.5; }
+h1 { font-size: 24pt; margin-top: 2em; }
+h2 { font-size: 20pt; margin-top: 1.5em; }
+h3 { font-size: 16pt; margin-top: 1.2em; }
+h4, h5, h6 { font-size: 14pt; }
+h1.chapter { text-align: center; page-break-before: always; }
+nav.toc ul { list-style: none; padding-left: 0; }
+blockquote { margin-left: 1em; padding-left: 1em; border-left: 3px solid #ccc; font-style: italic; }
+pre, code { font-family: Courier, monospace; font-size: 10pt; }
+pre { background: #f0f0f0; padding: 0.5em; overflow-x: auto; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #000; padding: 0.25em 0.5em; }
+th { background: #f0f0f0; }
+img { max-width: 100%; }
+`
+
+// CompileHTML writes all chapters as a single styled HTML document with an
+// anchor-linked table of contents. Chapters are discovered and ordered the
+// same way as for Compile, and each chapter's markdown is converted with
+// the same settings used for the PDF.
+//
+// Parameters:
+//   - w: Destination for the HTML document
+//
+// Returns:
+//   - error: Chapter discovery, file reading, or write errors
+func (bc *BookCompiler) CompileHTML(w io.Writer) error {
+	chapters, err := bc.getChapters()
+	if err != nil {
+		return fmt.Errorf("failed to get chapters: %w", err)
+	}
+
+	title := bc.bookTitle
+	if title == "" {
+		title = defaultHTMLTitle
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n",
+		html.EscapeString(title), htmlStylesheet)
+
+	fmt.Fprintf(out, "<nav class=\"toc\">\n<h1>%s</h1>\n<ul>\n", html.EscapeString(bc.tocTitle))
+	for _, chapter := range chapters {
+		fmt.Fprintf(out, "<li><a href=\"#%s\">%s</a></li>\n",
+			chapterAnchor(chapter.Path), html.EscapeString(chapterTitle(chapter)))
+	}
+	fmt.Fprint(out, "</ul>\n</nav>\n")
+
+	for _, chapter := range chapters {
+		fmt.Fprintf(out, "<section>\n<h1 class=\"chapter\" id=\"%s\">%s</h1>\n",
+			chapterAnchor(chapter.Path), html.EscapeString(chapterTitle(chapter)))
+		for _, file := range chapter.Files {
+			content, err := bc.readFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read file %s: %w", file, err)
+			}
+			_, content = splitFrontMatter(content)
+			out.Write(bc.markdownToHTML(content))
+		}
+		fmt.Fprint(out, "</section>\n")
+	}
+
+	fmt.Fprint(out, "</body>\n</html>\n")
+	return out.Flush()
+}
@@ -0,0 +1,248 @@
+package bookie
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Hyphenation limits, in letters, matching TeX's defaults for English.
+const (
+	hyphenLeftMin  = 2 // Fewest letters left before a hyphen
+	hyphenRightMin = 3 // Fewest letters carried to the next line
+)
+
+// englishPatterns is a compact set of Liang hyphenation patterns for
+// English: common prefixes and suffixes and breaks between consonants.
+// A dot marks a word boundary; an odd digit allows a break at its
+// position and an even digit forbids one. It is conservative, finding
+// fewer breaks than TeX's full pattern set, which can be loaded with
+// LoadHyphenationPatterns.
+const englishPatterns = `
+.un1b .un1c .un1d .un1f .un1h .un1l .un1p .un1r .un1s .un1t .un1w
+.dis1 .mis1 .pre1 .sub1 .inter1 .over1 .under1 .out1
+.re1c .re1f .re1g .re1m .re1p .re1t .re1v
+.con1c .con1d .con1f .con1n .con1s .con1t .con1v .com1b .com1m .com1p
+1tion 1sion 1ment 1ness 1less 1ful 1ture a1ble i1ble
+b1bing d1ding g1ging m1ming n1ning p1ping r1ring t1ting
+n2d1ing n2t1ing r2d1ing r2t1ing l2d1ing l2t1ing m2p1ing c2t1ing
+b1b c1c d1d f1f g1g l1l m1m n1n p1p r1r s1s t1t z1z
+m1b m1p n1c n1d n1s n1t c1t p1t
+l1b l1c l1d l1f l1m l1p l1t l1v
+r1b r1c r1d r1f r1g r1k r1l r1m r1n r1p r1s r1t r1v
+`
+
+// builtinHyphenation maps language codes to their embedded patterns.
+var builtinHyphenation = map[string]string{
+	"en":    englishPatterns,
+	"en-us": englishPatterns,
+	"en-gb": englishPatterns,
+}
+
+// hyphenator finds hyphenation points with Liang's algorithm.
+type hyphenator struct {
+	// patterns maps the letters of each pattern to its break values,
+	// one more than the number of letters
+	patterns map[string][]int
+
+	// exceptions maps words to their break positions, overriding the
+	// patterns
+	exceptions map[string][]int
+
+	// maxLen is the length in runes of the longest pattern
+	maxLen int
+}
+
+// newHyphenator parses hyphenation patterns in TeX format: whitespace
+// separated patterns such as "1tion" or ".un1b", optionally inside
+// \patterns{...}, and exceptions with explicit hyphens such as
+// "ta-ble", optionally inside \hyphenation{...}. Text after "%" on a line
+// is a comment.
+//
+// Parameters:
+//   - text: Pattern file content
+//
+// Returns:
+//   - *hyphenator: Hyphenator using the patterns
+func newHyphenator(text string) *hyphenator {
+	h := &hyphenator{patterns: make(map[string][]int), exceptions: make(map[string][]int)}
+	for _, line := range strings.Split(text, "\n") {
+		if comment := strings.IndexByte(line, '%'); comment >= 0 {
+			line = line[:comment]
+		}
+		for _, field := range strings.Fields(line) {
+			field = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(field, `\patterns{`), `\hyphenation{`), "}")
+			if field == "" || strings.ContainsAny(field, `\{}`) {
+				continue
+			}
+			if strings.Contains(field, "-") {
+				h.addException(field)
+			} else {
+				h.addPattern(field)
+			}
+		}
+	}
+	return h
+}
+
+// addPattern adds one Liang pattern, such as "a1ble".
+func (h *hyphenator) addPattern(pattern string) {
+	var letters []rune
+	values := []int{0}
+	for _, r := range strings.ToLower(pattern) {
+		if r >= '0' && r <= '9' {
+			values[len(values)-1] = int(r - '0')
+			continue
+		}
+		letters = append(letters, r)
+		values = append(values, 0)
+	}
+	h.patterns[string(letters)] = values
+	h.maxLen = max(h.maxLen, len(letters))
+}
+
+// addException adds a word with its hyphens marked, such as "ta-ble".
+func (h *hyphenator) addException(word string) {
+	var positions []int
+	letters := 0
+	for _, r := range strings.ToLower(word) {
+		if r == '-' {
+			positions = append(positions, letters)
+			continue
+		}
+		letters++
+	}
+	h.exceptions[strings.ReplaceAll(strings.ToLower(word), "-", "")] = positions
+}
+
+// breaks returns the positions at which a word may be hyphenated.
+//
+// Parameters:
+//   - word: A word of letters only
+//
+// Returns:
+//   - []int: Rune offsets, in increasing order, of the letters a hyphen
+//     may be placed before
+func (h *hyphenator) breaks(word string) []int {
+	lower := strings.ToLower(word)
+	letters := []rune(lower)
+	if len(letters) < hyphenLeftMin+hyphenRightMin {
+		return nil
+	}
+	if positions, ok := h.exceptions[lower]; ok {
+		return positions
+	}
+
+	text := []rune("." + lower + ".")
+	points := make([]int, len(text)+1)
+	for i := range text {
+		for j := i + 1; j <= len(text) && j-i <= h.maxLen; j++ {
+			values, ok := h.patterns[string(text[i:j])]
+			if !ok {
+				continue
+			}
+			for k, v := range values {
+				points[i+k] = max(points[i+k], v)
+			}
+		}
+	}
+
+	var positions []int
+	for i := hyphenLeftMin; i <= len(letters)-hyphenRightMin; i++ {
+		if points[i+1]%2 == 1 {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// LoadHyphenationPatterns reads Liang hyphenation patterns for a language
+// from a TeX pattern file, such as the hyph-*.pat.txt files of the
+// hyph-utf8 project, for use with SetHyphenation. Patterns loaded for
+// "en" replace the compact built-in English set.
+//
+// Parameters:
+//   - lang: Language code the patterns are registered under (e.g., "de")
+//   - path: Pattern file on the operating system's filesystem
+//
+// Returns:
+//   - error: If the file cannot be read
+func (bc *BookCompiler) LoadHyphenationPatterns(lang, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read hyphenation patterns: %w", err)
+	}
+	if bc.hyphenationPatterns == nil {
+		bc.hyphenationPatterns = make(map[string]*hyphenator)
+	}
+	bc.hyphenationPatterns[strings.ToLower(lang)] = newHyphenator(string(data))
+	return nil
+}
+
+// activeHyphenator returns the hyphenator for the language selected with
+// SetHyphenation, creating it from the built-in patterns on first use.
+//
+// Returns:
+//   - *hyphenator: The hyphenator, or nil if hyphenation is off or no
+//     patterns exist for the language
+func (bc *BookCompiler) activeHyphenator() *hyphenator {
+	if bc.hyphenation == "" {
+		return nil
+	}
+	if h, ok := bc.hyphenationPatterns[bc.hyphenation]; ok {
+		return h
+	}
+	patterns, ok := builtinHyphenation[bc.hyphenation]
+	if !ok {
+		return nil
+	}
+	if bc.hyphenationPatterns == nil {
+		bc.hyphenationPatterns = make(map[string]*hyphenator)
+	}
+	h := newHyphenator(patterns)
+	bc.hyphenationPatterns[bc.hyphenation] = h
+	return h
+}
+
+// hyphenateToFit splits a word so that its first part, followed by a
+// hyphen, fits in the given width in the current font, breaking at the
+// last hyphenation point that fits. Punctuation around the word stays
+// with the part it touches.
+//
+// Parameters:
+//   - word: Word about to be written
+//   - width: Space left on the line in millimeters
+//
+// Returns:
+//   - string: First part of the word with a hyphen appended
+//   - string: Rest of the word
+//   - bool: false if hyphenation is off or no break fits
+func (bc *BookCompiler) hyphenateToFit(word string, width float64) (string, string, bool) {
+	h := bc.activeHyphenator()
+	if h == nil {
+		return "", "", false
+	}
+
+	runes := []rune(word)
+	start, end := 0, len(runes)
+	for start < end && !unicode.IsLetter(runes[start]) {
+		start++
+	}
+	for end > start && !unicode.IsLetter(runes[end-1]) {
+		end--
+	}
+	core := string(runes[start:end])
+	if strings.IndexFunc(core, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+		return "", "", false
+	}
+
+	positions := h.breaks(core)
+	for i := len(positions) - 1; i >= 0; i-- {
+		head := string(runes[:start+positions[i]]) + "-"
+		if bc.pdf.GetStringWidth(head) <= width {
+			return head, string(runes[start+positions[i]:]), true
+		}
+	}
+	return "", "", false
+}
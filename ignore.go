@@ -0,0 +1,170 @@
+package bookie
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFileName is the file in a source directory listing paths to leave
+// out of the book, one gitignore-style pattern per line.
+const ignoreFileName = ".bookieignore"
+
+// ignoreRule is one compiled ignore pattern.
+type ignoreRule struct {
+	// pattern matches slash-separated paths relative to the source
+	// directory, or base names when the pattern contains no slash
+	pattern *regexp.Regexp
+
+	// basename is true when the pattern is matched against each path
+	// component instead of the whole path
+	basename bool
+
+	// dirOnly is true for patterns ending in "/", which match only
+	// directories
+	dirOnly bool
+
+	// negate is true for patterns starting with "!", which include
+	// again paths excluded by earlier patterns
+	negate bool
+}
+
+// parseIgnorePatterns compiles gitignore-style patterns. Blank lines and
+// lines starting with "#" are skipped; "*" and "?" match within a path
+// component and "**" across components; a leading "/" or a slash inside
+// the pattern anchors it to the source directory, otherwise it matches a
+// file or directory name at any depth; a trailing "/" matches directories
+// only; a leading "!" includes again a path excluded by an earlier
+// pattern.
+//
+// Parameters:
+//   - patterns: Patterns in the order they apply
+//
+// Returns:
+//   - []ignoreRule: Compiled rules
+func parseIgnorePatterns(patterns []string) []ignoreRule {
+	var rules []ignoreRule
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(pattern, "!") {
+			rule.negate = true
+			pattern = pattern[1:]
+		}
+		if strings.HasSuffix(pattern, "/") {
+			rule.dirOnly = true
+			pattern = strings.TrimRight(pattern, "/")
+		}
+		rule.basename = !strings.Contains(pattern, "/")
+		pattern = strings.TrimPrefix(pattern, "/")
+		if pattern == "" {
+			continue
+		}
+		rule.pattern = regexp.MustCompile("^" + globToRegexp(pattern) + "$")
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// globToRegexp translates a gitignore glob to a regular expression.
+func globToRegexp(glob string) string {
+	var expr strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			expr.WriteString("[^/]*")
+		case glob[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return expr.String()
+}
+
+// matches reports whether a rule applies to a path.
+//
+// Parameters:
+//   - rel: Slash-separated path relative to the source directory
+//   - isDir: Whether the path is a directory
+func (rule ignoreRule) matches(rel string, isDir bool) bool {
+	if rule.dirOnly && !isDir {
+		return false
+	}
+	if rule.basename {
+		return rule.pattern.MatchString(rel[strings.LastIndex(rel, "/")+1:])
+	}
+	return rule.pattern.MatchString(rel)
+}
+
+// loadIgnoreRules prepares the ignore rules for scanning a source
+// directory: the patterns set with SetIgnorePatterns followed by those in
+// the directory's .bookieignore file, if it has one.
+//
+// Parameters:
+//   - root: Source directory about to be scanned
+//
+// Returns:
+//   - error: Errors reading an existing .bookieignore file
+func (bc *BookCompiler) loadIgnoreRules(root string) error {
+	patterns := append([]string(nil), bc.ignorePatterns...)
+	data, err := bc.readFile(filepath.Join(root, ignoreFileName))
+	switch {
+	case err == nil:
+		patterns = append(patterns, strings.Split(string(data), "\n")...)
+	case !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("failed to read %s: %w", ignoreFileName, err)
+	}
+
+	bc.ignoreRoot = root
+	bc.ignoreRules = parseIgnorePatterns(patterns)
+	return nil
+}
+
+// isIgnored reports whether a path inside the source directory being
+// scanned is excluded by the ignore rules, either itself or through one
+// of its parent directories.
+//
+// Parameters:
+//   - path: Path of a file or directory, including the source directory
+//   - isDir: Whether the path is a directory
+//
+// Returns:
+//   - bool: true if the path should be left out of the book
+func (bc *BookCompiler) isIgnored(path string, isDir bool) bool {
+	if len(bc.ignoreRules) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(bc.ignoreRoot, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], "/")
+		ignored := false
+		for _, rule := range bc.ignoreRules {
+			if rule.matches(prefix, isDir || i < len(parts)-1) {
+				ignored = !rule.negate
+			}
+		}
+		if ignored {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package bookie
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+)
+
+// Image downsampling parameters.
+const (
+	mmPerInch          = 25.4
+	downsampleQuality  = 90  // JPEG quality of downsampled images
+	downsampleMinRatio = 1.1 // Images less than this far above the target are kept
+)
+
+// downsampleImage reduces a JPEG image to the configured resolution at
+// its display width. Images that are not JPEGs, are already at or below
+// the target resolution, or cannot be decoded are returned unchanged.
+//
+// Parameters:
+//   - name: Image path, for warnings
+//   - data: Encoded image
+//   - width: Display width in millimeters
+//
+// Returns:
+//   - []byte: The re-encoded image, or data unchanged
+func (bc *BookCompiler) downsampleImage(name string, data []byte, width float64) []byte {
+	if !isJPEGImage(name) {
+		return data
+	}
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return data
+	}
+	targetWidth := int(width / mmPerInch * float64(bc.imageDPI))
+	if targetWidth < 1 || float64(config.Width) < float64(targetWidth)*downsampleMinRatio {
+		return data
+	}
+	targetHeight := config.Height * targetWidth / config.Width
+	if targetHeight < 1 {
+		return data
+	}
+
+	src, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		bc.logWarning("Could not downsample %s: %v", name, err)
+		return data
+	}
+
+	var out bytes.Buffer
+	err = jpeg.Encode(&out, scaleImage(src, targetWidth, targetHeight), &jpeg.Options{Quality: downsampleQuality})
+	if err != nil || out.Len() >= len(data) {
+		return data
+	}
+	return out.Bytes()
+}
+
+// scaleImage shrinks an image to the given size by averaging the source
+// pixels covered by each destination pixel.
+//
+// Parameters:
+//   - src: Image to shrink
+//   - width, height: Size of the result in pixels, no larger than src
+//
+// Returns:
+//   - *image.RGBA: The scaled image
+func scaleImage(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(b / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,105 @@
+package bookie
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// Index layout constants
+const (
+	defaultIndexTitle = "Index" // Title of the generated index section
+	indexLetterSize   = 14.0    // Font size for index letter group headings
+	indexEntrySize    = 11.0    // Font size for index entries
+)
+
+// indexMarkerPattern matches index term markers written as HTML comments,
+// e.g. <!-- index: goroutine -->. The comment delimiters are optional so
+// the pattern applies to both raw markdown HTML and parsed comment data.
+var indexMarkerPattern = regexp.MustCompile(`^\s*(?:<!--)?\s*index:\s*(.+?)\s*(?:-->)?\s*$`)
+
+// parseIndexMarker extracts the term from an index marker.
+//
+// Parameters:
+//   - text: HTML comment, either raw ("<!-- index: term -->") or its data
+//
+// Returns:
+//   - string: The marked term
+//   - bool: false if text is not an index marker
+func parseIndexMarker(text string) (string, bool) {
+	matches := indexMarkerPattern.FindStringSubmatch(text)
+	if len(matches) < 2 || matches[1] == "" {
+		return "", false
+	}
+	return matches[1], true
+}
+
+// recordIndexTerm records the current page for an index marker comment.
+// Comments that are not index markers are ignored.
+//
+// Parameters:
+//   - n: HTML comment node
+func (bc *BookCompiler) recordIndexTerm(n *html.Node) {
+	term, ok := parseIndexMarker(n.Data)
+	if !ok {
+		return
+	}
+
+	page := bc.pdf.PageNo()
+	pages := bc.indexTerms[term]
+	if len(pages) == 0 || pages[len(pages)-1] != page {
+		bc.indexTerms[term] = append(pages, page)
+	}
+}
+
+// renderIndex renders the alphabetical index of marked terms as the final
+// section of the book. Terms are grouped under their initial letter and
+// list every page on which they were marked.
+func (bc *BookCompiler) renderIndex() {
+	terms := make([]string, 0, len(bc.indexTerms))
+	for term := range bc.indexTerms {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return strings.ToLower(terms[i]) < strings.ToLower(terms[j])
+	})
+
+	bc.currentChapter = matterSection{title: bc.indexTitle}
+	bc.ensureChapterBreak(bc.indexTitle)
+	bc.renderTitle(bc.indexTitle)
+
+	var group rune
+	for _, term := range terms {
+		if initial := indexGroup(term); initial != group {
+			group = initial
+			bc.pdf.Ln(defaultLineHeight)
+			bc.setFont(bc.chapterFont, fontStyleBold, indexLetterSize)
+			bc.pdf.Write(defaultLineHeight*1.5, string(group))
+			bc.pdf.Ln(defaultLineHeight * 1.5)
+		}
+
+		labels := make([]string, len(bc.indexTerms[term]))
+		for i, page := range bc.indexTerms[term] {
+			labels[i] = bc.pageLabel(page, bc.bodyStartPage)
+		}
+
+		bc.setFont(bc.textFont, fontStyleNormal, indexEntrySize)
+		bc.writeText(term + ", " + strings.Join(labels, ", "))
+		bc.pdf.Ln(defaultLineHeight)
+	}
+}
+
+// indexGroup returns the uppercase letter an index term is grouped under,
+// or '#' for terms starting with a digit or symbol.
+func indexGroup(term string) rune {
+	for _, r := range term {
+		if unicode.IsLetter(r) {
+			return unicode.ToUpper(r)
+		}
+		return '#'
+	}
+	return '#'
+}
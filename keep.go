@@ -0,0 +1,244 @@
+package bookie
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// keepTogetherClass marks a div whose content should not be split across
+// pages, e.g. a code listing with its caption.
+const keepTogetherClass = "keep-together"
+
+// codeBlockSpacing is the space renderCode leaves below a code block.
+const codeBlockSpacing = 8.0
+
+// expandMarkdown replaces the content of an element holding only text with
+// that text converted from markdown. Math already marked in the text is
+// kept. Elements with other child elements are left unchanged, as they
+// already contain HTML.
+//
+// Parameters:
+//   - n: Element whose content may be markdown
+//
+// Returns:
+//   - error: If the converted HTML cannot be parsed
+func (bc *BookCompiler) expandMarkdown(n *html.Node) error {
+	var text strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.TextNode:
+			text.WriteString(c.Data)
+		case c.Type == html.ElementNode && c.Data == "span" && hasClass(c, mathClass):
+			text.WriteString(mathSource(c))
+		default:
+			return nil
+		}
+	}
+	if strings.TrimSpace(text.String()) == "" {
+		return nil
+	}
+
+	content := bc.markdownToHTML([]byte(text.String()))
+	nodes, err := html.ParseFragment(bytes.NewReader(content), n)
+	if err != nil {
+		return fmt.Errorf("failed to parse markdown in %s: %w", n.Data, err)
+	}
+	for n.FirstChild != nil {
+		n.RemoveChild(n.FirstChild)
+	}
+	for _, node := range nodes {
+		n.AppendChild(node)
+	}
+	prepareContent(n)
+	return nil
+}
+
+// keepTogether starts a new page if a block's estimated height does not
+// fit in the space left on the current page. Blocks taller than a whole
+// page, and blocks at the top of a page, are left to break normally.
+//
+// Parameters:
+//   - n: Block element about to be rendered
+func (bc *BookCompiler) keepTogether(n *html.Node) {
+	_, top, _, _ := bc.pdf.GetMargins()
+	_, bottom := bc.pdf.GetAutoPageBreak()
+	pageSpace := bc.getPageHeight() - top - bottom
+
+	height := bc.estimateHeight(n)
+	if bc.pdf.GetY() > top && height <= pageSpace && bc.pdf.GetY()+height > bc.getPageHeight()-bottom {
+		bc.pdf.AddPage()
+	}
+}
+
+// estimateHeight estimates the height the children of an element take
+// when rendered, from their wrapped line counts, image sizes, and the
+// configured spacing. The space below the last child is not included, as
+// it may extend past the bottom of the page.
+//
+// Parameters:
+//   - n: Element whose content is measured
+//
+// Returns:
+//   - float64: Estimated height in millimeters
+func (bc *BookCompiler) estimateHeight(n *html.Node) float64 {
+	previous := bc.font
+	defer bc.restoreTextState(previous)
+
+	height, spaceAfter := 0.0, 0.0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		childHeight, childSpaceAfter := bc.nodeHeight(c)
+		if childHeight == 0 {
+			continue
+		}
+		height += spaceAfter + childHeight
+		spaceAfter = childSpaceAfter
+	}
+	return height
+}
+
+// nodeHeight estimates the rendered height of one node for
+// estimateHeight. It changes the PDF font to measure text.
+//
+// Parameters:
+//   - n: Node to measure
+//
+// Returns:
+//   - float64: Estimated height in millimeters, including space above
+//   - float64: Space left below the node in millimeters
+func (bc *BookCompiler) nodeHeight(n *html.Node) (float64, float64) {
+	if n.Type == html.TextNode {
+		return bc.textHeight(n.Data, TextStyle{FontFamily: bc.textFont, Size: defaultFontSize}), 0
+	}
+	if n.Type != html.ElementNode {
+		return 0, 0
+	}
+
+	switch {
+	case isHeading(n):
+		style := bc.styleForHeading(int(n.Data[1] - '0'))
+		return style.spaceBefore + style.text.Size/bc.pdf.GetConversionRatio(), style.spaceAfter
+	case n.Data == "img", n.Data == "figure":
+		return bc.imageHeight(n), defaultLineHeight
+	case n.Data == "table":
+		return bc.tableHeight(n), 0
+	case n.Data == "div":
+		if _, ok := elementCallout(n); ok {
+			return defaultLineHeight + 2*calloutPadding + bc.lineHeight() + bc.estimateHeight(n), defaultLineHeight
+		}
+	case n.Data == "pre":
+		before, after := bc.spacingFor("code", defaultLineHeight, defaultLineHeight)
+		style := bc.styleFor("code", TextStyle{FontFamily: "Courier", Size: 10})
+		return before + bc.textHeight(getTextContent(n), style), codeBlockSpacing + after
+	case n.Data == "p":
+		if findDescendant(n, "img") != nil {
+			return bc.imageHeight(n), defaultLineHeight
+		}
+		style, _ := bc.paragraphStyle(n)
+		before, after := bc.spacingFor("p", bc.paragraphSpacing, 0)
+		return before + bc.textHeight(getTextContent(n), style), after
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && !isInline(c) {
+			return bc.estimateHeight(n), 0
+		}
+	}
+	return bc.textHeight(getTextContent(n), TextStyle{FontFamily: bc.textFont, Size: defaultFontSize}), 0
+}
+
+// textHeight estimates the height of text wrapped across the content
+// width in the given font.
+//
+// Parameters:
+//   - text: Raw text
+//   - style: Font the text is rendered in
+//
+// Returns:
+//   - float64: Height in millimeters; zero for blank text
+func (bc *BookCompiler) textHeight(text string, style TextStyle) float64 {
+	text = strings.TrimSpace(bc.cleanText(text))
+	if text == "" {
+		return 0
+	}
+	width, _ := bc.pdf.GetPageSize()
+	left, _, right, _ := bc.pdf.GetMargins()
+	return float64(len(bc.SplitTextInFont(text, width-left-right, style))) * bc.lineHeight()
+}
+
+// imageHeight estimates the height of an image as drawn by handleImage,
+// including its caption and the space above it.
+//
+// Parameters:
+//   - n: Image element, or an element containing one
+//
+// Returns:
+//   - float64: Height in millimeters; zero if the image cannot be loaded
+func (bc *BookCompiler) imageHeight(n *html.Node) float64 {
+	img := n
+	if n.Data != "img" {
+		img = findDescendant(n, "img")
+	}
+	if img == nil {
+		return 0
+	}
+	path, err := bc.resolveImagePath(getAttr(img, "src"))
+	if err == nil && isSVGImage(path) && bc.svgRasterizer != nil {
+		path, err = bc.rasterizeSVG(path)
+	}
+	if err != nil || !isJPEGImage(path) {
+		return 0
+	}
+	info := bc.registerImage(path, imageWidth)
+	if info == nil {
+		return 0
+	}
+
+	height := defaultLineHeight + info.Height()*imageWidth/info.Width() + 5
+	if getAttr(img, "alt") != "" || findDescendant(n, "figcaption") != nil {
+		height += bc.lineHeight()
+	}
+	return height
+}
+
+// tableHeight estimates the height of a table rendered at full width,
+// including its caption.
+//
+// Parameters:
+//   - n: Table element
+//
+// Returns:
+//   - float64: Height in millimeters; zero for malformed tables
+func (bc *BookCompiler) tableHeight(n *html.Node) float64 {
+	headers, rows, footers, err := bc.parseTableStructure(n)
+	if err != nil {
+		return 0
+	}
+	colCount := bc.determineColumnCount(headers, append(rows, footers...))
+	if colCount == 0 {
+		return 0
+	}
+
+	fontSize := bc.tableSize()
+	lineHeight := tableLineHeight * fontSize / tableFontSize
+	colWidth := tableWidth / float64(colCount)
+	font := TextStyle{FontFamily: bc.tableFamily(), Size: fontSize}
+	bold := TextStyle{FontFamily: font.FontFamily, Style: fontStyleBold, Size: fontSize}
+
+	height := 0.0
+	if findDescendant(n, "caption") != nil || markdownTableCaption(n) != "" {
+		height += bc.lineHeight()
+	}
+	if len(headers) > 0 {
+		height += bc.calculateRowHeight(headers, colWidth, lineHeight, bold)
+	}
+	for _, row := range rows {
+		height += bc.calculateRowHeight(row, colWidth, lineHeight, font)
+	}
+	for _, row := range footers {
+		height += bc.calculateRowHeight(row, colWidth, lineHeight, bold)
+	}
+	return height
+}
@@ -0,0 +1,69 @@
+package bookie
+
+// kernPairs holds kerning adjustments for common Latin letter pairs in
+// thousandths of an em. gofpdf core font metrics carry no kerning data, so
+// these values approximate the pair kerning of the Helvetica and Times
+// families used for titles.
+var kernPairs = map[[2]rune]int{
+	{'A', 'T'}: -90, {'A', 'V'}: -80, {'A', 'W'}: -60, {'A', 'Y'}: -100,
+	{'A', 'v'}: -40, {'A', 'w'}: -30, {'A', 'y'}: -40,
+	{'F', 'A'}: -80, {'F', 'a'}: -50, {'F', 'o'}: -30, {'F', ','}: -100, {'F', '.'}: -100,
+	{'L', 'T'}: -90, {'L', 'V'}: -100, {'L', 'W'}: -80, {'L', 'Y'}: -110, {'L', 'y'}: -50,
+	{'P', 'A'}: -90, {'P', ','}: -120, {'P', '.'}: -120,
+	{'T', 'A'}: -90, {'T', 'a'}: -80, {'T', 'e'}: -80, {'T', 'o'}: -80,
+	{'T', 'r'}: -60, {'T', 'u'}: -60, {'T', 'y'}: -60, {'T', ','}: -100, {'T', '.'}: -100,
+	{'V', 'A'}: -80, {'V', 'a'}: -60, {'V', 'e'}: -60, {'V', 'o'}: -60, {'V', ','}: -100, {'V', '.'}: -100,
+	{'W', 'A'}: -60, {'W', 'a'}: -40, {'W', 'e'}: -40, {'W', 'o'}: -40, {'W', ','}: -80, {'W', '.'}: -80,
+	{'Y', 'A'}: -100, {'Y', 'a'}: -90, {'Y', 'e'}: -90, {'Y', 'o'}: -90, {'Y', ','}: -110, {'Y', '.'}: -110,
+	{'r', ','}: -50, {'r', '.'}: -50, {'v', ','}: -60, {'v', '.'}: -60,
+	{'w', ','}: -50, {'w', '.'}: -50, {'y', ','}: -60, {'y', '.'}: -60,
+}
+
+// kernAdjustment returns the kerning between two adjacent runes in
+// thousandths of an em, or 0 if the pair is not kerned.
+func kernAdjustment(left, right rune) int {
+	return kernPairs[[2]rune{left, right}]
+}
+
+// kernedStringWidth measures text in the current font with pair kerning
+// applied.
+//
+// Parameters:
+//   - text: Text to measure
+//
+// Returns:
+//   - float64: Width in millimeters, never more than the unkerned width
+func (bc *BookCompiler) kernedStringWidth(text string) float64 {
+	_, fontSize := bc.pdf.GetFontSize()
+	width := bc.pdf.GetStringWidth(text)
+
+	runes := []rune(text)
+	for i := 1; i < len(runes); i++ {
+		width += float64(kernAdjustment(runes[i-1], runes[i])) * fontSize / 1000
+	}
+	return width
+}
+
+// writeKernedCell draws single-line text with pair kerning at x on the
+// current line, vertically positioned as gofpdf positions Cell text.
+// Each rune is placed individually, so the text does not wrap.
+//
+// Parameters:
+//   - x: Left edge of the text in millimeters
+//   - height: Cell height in millimeters
+//   - text: Text to draw
+func (bc *BookCompiler) writeKernedCell(x, height float64, text string) {
+	_, fontSize := bc.pdf.GetFontSize()
+	baseline := bc.pdf.GetY() + height/2 + 0.3*fontSize
+
+	runes := []rune(text)
+	for i, r := range runes {
+		glyph := string(r)
+		bc.pdf.Text(x, baseline, glyph)
+		x += bc.pdf.GetStringWidth(glyph)
+		if i < len(runes)-1 {
+			x += float64(kernAdjustment(r, runes[i+1])) * fontSize / 1000
+		}
+	}
+	bc.pdf.SetX(x)
+}
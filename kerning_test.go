@@ -0,0 +1,17 @@
+package bookie
+
+import "testing"
+
+func TestKernedWidthIsNarrower(t *testing.T) {
+	bc := newTestCompiler(t)
+	bc.setFont(bc.chapterFont, chapterTitleFont, chapterTitleSize)
+
+	plain := bc.pdf.GetStringWidth("AVA")
+	kerned := bc.kernedStringWidth("AVA")
+	if kerned >= plain {
+		t.Errorf("kerned width of AVA is %.2fmm, want less than the unkerned %.2fmm", kerned, plain)
+	}
+	if text := "HHH"; bc.kernedStringWidth(text) != bc.pdf.GetStringWidth(text) {
+		t.Errorf("kerned width of %s differs from the unkerned width, want no kerning", text)
+	}
+}
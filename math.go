@@ -0,0 +1,509 @@
+package bookie
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Math markup, as written by markMath and read back by renderMath.
+const (
+	mathClass        = "math"       // Class of spans holding math
+	mathDisplayClass = "display"    // Additional class of display math spans
+	mathAttr         = "data-latex" // Attribute holding the LaTeX source
+)
+
+// Built-in math layout relative to the surrounding font size.
+const (
+	mathScriptScale = 0.7  // Size of superscripts and subscripts
+	mathSupRaise    = 0.35 // Superscript raise, as a fraction of the font size
+	mathSubDrop     = 0.15 // Subscript drop, as a fraction of the font size
+)
+
+// mathSymbols maps LaTeX commands to the characters the built-in renderer
+// draws for them. Characters outside the core fonts need a glyph fallback
+// font.
+var mathSymbols = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ε",
+	"zeta": "ζ", "eta": "η", "theta": "θ", "kappa": "κ", "lambda": "λ",
+	"mu": "µ", "nu": "ν", "xi": "ξ", "pi": "π", "rho": "ρ", "sigma": "σ",
+	"tau": "τ", "phi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Pi": "Π",
+	"Sigma": "Σ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+	"times": "×", "cdot": "·", "pm": "±", "div": "÷", "ast": "*",
+	"le": "≤", "leq": "≤", "ge": "≥", "geq": "≥", "ne": "≠", "neq": "≠",
+	"approx": "≈", "equiv": "≡", "sim": "~", "propto": "∝",
+	"infty": "∞", "partial": "∂", "nabla": "∇", "sum": "Σ", "prod": "Π",
+	"int": "∫", "in": "∈", "notin": "∉", "subset": "⊂", "cup": "∪", "cap": "∩",
+	"forall": "∀", "exists": "∃", "emptyset": "∅", "neg": "¬",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "Rightarrow": "⇒",
+	"leftrightarrow": "↔", "Leftrightarrow": "⇔",
+	"ldots": "…", "cdots": "⋯", "dots": "…", "prime": "′", "circ": "°",
+}
+
+// mathFunctions are LaTeX commands for functions, drawn upright by name.
+var mathFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true,
+	"arcsin": true, "arccos": true, "arctan": true, "sinh": true, "cosh": true,
+	"tanh": true, "log": true, "ln": true, "exp": true, "lim": true, "max": true,
+	"min": true, "det": true, "gcd": true, "deg": true, "dim": true, "mod": true,
+}
+
+// mathRun is a piece of math drawn by the built-in renderer in one font
+// and position.
+type mathRun struct {
+	// text is the run's characters
+	text string
+
+	// shift is 1 for a superscript, -1 for a subscript, and 0 on the
+	// baseline
+	shift int
+
+	// upright runs (function names and \text) are drawn in the regular
+	// text style; other runs are italic
+	upright bool
+}
+
+// markMath finds inline ($...$) and display ($$...$$) math in markdown
+// outside code and replaces it with empty spans holding the LaTeX source
+// in an attribute, which blackfriday passes through unchanged, so that
+// markdown syntax within it, such as "_" and "*", is left alone. An
+// inline opening "$" must be followed by a non-space and its closing "$"
+// preceded by a non-space and not followed by a digit, so prices such as
+// "$5 and $10" stay text; "\$" writes a literal dollar sign.
+//
+// Parameters:
+//   - content: Markdown content
+//
+// Returns:
+//   - []byte: The content with math marked
+func markMath(content []byte) []byte {
+	if !strings.Contains(string(content), "$") {
+		return content
+	}
+
+	var out, prose strings.Builder
+	inCode := false
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if codeFence.MatchString(strings.TrimRight(line, "\r\n")) {
+			if !inCode {
+				out.WriteString(markMathText(prose.String()))
+				prose.Reset()
+			}
+			inCode = !inCode
+			out.WriteString(line)
+			continue
+		}
+		if inCode {
+			out.WriteString(line)
+		} else {
+			prose.WriteString(line)
+		}
+	}
+	out.WriteString(markMathText(prose.String()))
+	return []byte(out.String())
+}
+
+// markMathText marks the math in markdown text without code blocks, for
+// markMath.
+func markMathText(text string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		switch {
+		case text[i] == '\\' && i+1 < len(text):
+			if text[i+1] == '$' {
+				out.WriteByte('$')
+			} else {
+				out.WriteString(text[i : i+2])
+			}
+			i += 2
+		case text[i] == '`':
+			run := len(text[i:]) - len(strings.TrimLeft(text[i:], "`"))
+			end := strings.Index(text[i+run:], text[i:i+run])
+			if end < 0 {
+				out.WriteString(text[i : i+run])
+				i += run
+				continue
+			}
+			out.WriteString(text[i : i+2*run+end])
+			i += 2*run + end
+		case strings.HasPrefix(text[i:], "$$"):
+			end := strings.Index(text[i+2:], "$$")
+			if end < 0 {
+				out.WriteString("$$")
+				i += 2
+				continue
+			}
+			writeMathSpan(&out, text[i+2:i+2+end], true)
+			i += end + 4
+		case text[i] == '$':
+			end := inlineMathEnd(text, i)
+			if end < 0 {
+				out.WriteByte('$')
+				i++
+				continue
+			}
+			writeMathSpan(&out, text[i+1:end], false)
+			i = end + 1
+		default:
+			out.WriteByte(text[i])
+			i++
+		}
+	}
+	return out.String()
+}
+
+// inlineMathEnd finds the "$" closing inline math opened at start.
+//
+// Parameters:
+//   - text: Markdown text
+//   - start: Index of the opening "$"
+//
+// Returns:
+//   - int: Index of the closing "$", or -1 if the math is not closed
+//     within the paragraph or before a code span
+func inlineMathEnd(text string, start int) int {
+	if start+1 >= len(text) || unicode.IsSpace(rune(text[start+1])) {
+		return -1
+	}
+	for i := start + 2; i < len(text); i++ {
+		switch {
+		case text[i] == '\\':
+			i++
+		case text[i] == '`', text[i] == '\n' && isBlankLine(text[i+1:]):
+			return -1
+		case text[i] == '$':
+			if unicode.IsSpace(rune(text[i-1])) || (i+1 < len(text) && text[i+1] >= '0' && text[i+1] <= '9') {
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// isBlankLine reports whether the first line of text is blank, ending a
+// paragraph.
+func isBlankLine(text string) bool {
+	line, _, _ := strings.Cut(text, "\n")
+	return strings.TrimSpace(line) == ""
+}
+
+// writeMathSpan writes math as a span for markMath.
+//
+// Parameters:
+//   - out: Markdown being built
+//   - latex: LaTeX source between the dollar signs
+//   - display: Whether the math is display math
+func writeMathSpan(out *strings.Builder, latex string, display bool) {
+	class := mathClass
+	if display {
+		class += " " + mathDisplayClass
+	}
+	latex = html.EscapeString(strings.Join(strings.Fields(latex), " "))
+	fmt.Fprintf(out, `<span class="%s" %s="%s"></span>`, class, mathAttr, latex)
+}
+
+// mathSource returns the markdown a math span was made from, so that
+// content converted again by expandMarkdown keeps its math.
+func mathSource(n *html.Node) string {
+	if hasClass(n, mathDisplayClass) {
+		return "$$" + getAttr(n, mathAttr) + "$$"
+	}
+	return "$" + getAttr(n, mathAttr) + "$"
+}
+
+// renderMath renders a math span. With a math renderer set, its image is
+// embedded: display math through handleImage, inline math scaled to the
+// line height. Otherwise the built-in renderer draws the expression as
+// text, centered on its own line for display math.
+//
+// Parameters:
+//   - n: Math span
+//
+// Returns:
+//   - error: Math renderer or image loading errors
+func (bc *BookCompiler) renderMath(n *html.Node) error {
+	latex := strings.TrimSpace(getAttr(n, mathAttr))
+	if latex == "" {
+		return nil
+	}
+	display := hasClass(n, mathDisplayClass)
+
+	if bc.mathRenderer == nil {
+		runs := parseMath(latex)
+		if display {
+			bc.drawDisplayMath(runs, latex)
+		} else {
+			page := bc.beginMarkedContent("Formula", latex)
+			bc.drawMath(runs)
+			bc.endMarkedContent(page)
+		}
+		return nil
+	}
+
+	path, err := bc.renderedImage("math", latex, bc.mathRenderer)
+	if err != nil {
+		return fmt.Errorf("failed to render math %q: %w", latex, err)
+	}
+	if display {
+		return bc.handleImage(path, "", latex)
+	}
+	return bc.drawInlineMathImage(path, latex)
+}
+
+// drawInlineMathImage draws a math image within the current line, scaled
+// to the line height, moving to the next line first if it does not fit.
+//
+// Parameters:
+//   - path: Image path
+//   - latex: LaTeX source, used as alternate text
+//
+// Returns:
+//   - error: Image loading errors
+func (bc *BookCompiler) drawInlineMathImage(path, latex string) error {
+	height := bc.lineHeight()
+	info := bc.registerImage(path, 0) // the display width is not known yet, so it is not downsampled
+	if info == nil {
+		return fmt.Errorf("failed to load image: %s", path)
+	}
+	width := info.Width() * height / info.Height()
+
+	pageWidth, _ := bc.pdf.GetPageSize()
+	_, _, right, _ := bc.pdf.GetMargins()
+	if bc.pdf.GetX()+width > pageWidth-right {
+		bc.pdf.Ln(height)
+	}
+
+	x, y := bc.pdf.GetX(), bc.pdf.GetY()
+	page := bc.beginMarkedContent("Formula", latex)
+	bc.pdf.Image(path, x, y, width, height, false, "", 0, "")
+	bc.endMarkedContent(page)
+	bc.pdf.SetXY(x+width, y)
+	return nil
+}
+
+// drawDisplayMath draws built-in math centered on its own line.
+//
+// Parameters:
+//   - runs: Parsed math
+//   - latex: LaTeX source, used as alternate text
+func (bc *BookCompiler) drawDisplayMath(runs []mathRun, latex string) {
+	previous := bc.font
+	width := 0.0
+	for _, run := range runs {
+		bc.setMathFont(run, previous)
+		width += bc.pdf.GetStringWidth(bc.cleanText(run.text))
+	}
+	bc.restoreTextState(previous)
+
+	pageWidth, _ := bc.pdf.GetPageSize()
+	left, _, right, _ := bc.pdf.GetMargins()
+	bc.pdf.Ln(defaultLineHeight)
+	bc.pdf.SetX(left + max(0, (pageWidth-left-right-width)/2))
+
+	page := bc.beginMarkedContent("Formula", latex)
+	bc.drawMath(runs)
+	bc.endMarkedContent(page)
+	bc.pdf.Ln(bc.lineHeight() + defaultLineHeight)
+}
+
+// drawMath writes built-in math at the current position in the current
+// font size, raising superscripts and lowering subscripts.
+//
+// Parameters:
+//   - runs: Parsed math
+func (bc *BookCompiler) drawMath(runs []mathRun) {
+	previous := bc.font
+	offset := previous.Size / bc.pdf.GetConversionRatio()
+	for _, run := range runs {
+		bc.setMathFont(run, previous)
+		shift := 0.0
+		switch {
+		case run.shift > 0:
+			shift = -offset * mathSupRaise
+		case run.shift < 0:
+			shift = offset * mathSubDrop
+		}
+
+		bc.pdf.SetXY(bc.pdf.GetX(), bc.pdf.GetY()+shift)
+		bc.writeText(run.text)
+		bc.pdf.SetXY(bc.pdf.GetX(), bc.pdf.GetY()-shift)
+	}
+	bc.restoreTextState(previous)
+}
+
+// setMathFont selects the font for a run of built-in math.
+//
+// Parameters:
+//   - run: Math run to draw
+//   - base: Font of the surrounding text
+func (bc *BookCompiler) setMathFont(run mathRun, base TextState) {
+	style := fontStyleItalic
+	if run.upright {
+		style = fontStyleNormal
+	}
+	size := base.Size
+	if run.shift != 0 {
+		size *= mathScriptScale
+	}
+	bc.setFont(bc.textFont, style, size)
+}
+
+// parseMath converts simple LaTeX to runs of text for the built-in
+// renderer. It understands the symbols in mathSymbols, function names,
+// superscripts, subscripts, \frac, \sqrt, and \text; other commands are
+// drawn by name.
+//
+// Parameters:
+//   - latex: LaTeX source
+//
+// Returns:
+//   - []mathRun: Runs in drawing order
+func parseMath(latex string) []mathRun {
+	var runs []mathRun
+	emit := func(text string, shift int, upright bool) {
+		if text == "" {
+			return
+		}
+		if last := len(runs) - 1; last >= 0 && runs[last].shift == shift && runs[last].upright == upright {
+			runs[last].text += text
+			return
+		}
+		runs = append(runs, mathRun{text: text, shift: shift, upright: upright})
+	}
+
+	var parse func(s string, shift int)
+	parse = func(s string, shift int) {
+		for i := 0; i < len(s); {
+			switch c := s[i]; {
+			case c == '{' || c == '}':
+				i++
+			case c == '^' || c == '_':
+				arg, next := mathArgument(s, i+1)
+				script := 1
+				if c == '_' {
+					script = -1
+				}
+				parse(arg, script)
+				i = next
+			case c == '\\':
+				name, next := mathCommand(s, i)
+				i = next
+				switch {
+				case name == "frac":
+					num, afterNum := mathArgument(s, i)
+					den, afterDen := mathArgument(s, afterNum)
+					parse(mathGroup(num), shift)
+					emit("/", shift, true)
+					parse(mathGroup(den), shift)
+					i = afterDen
+				case name == "sqrt":
+					arg, after := mathArgument(s, i)
+					emit("√", shift, true)
+					parse(mathGroup(arg), shift)
+					i = after
+				case name == "text" || name == "mathrm" || name == "operatorname":
+					arg, after := mathArgument(s, i)
+					emit(arg, shift, true)
+					i = after
+				case name == "left" || name == "right" || name == "!":
+				case name == "," || name == ";" || name == ":" || name == " " || name == "quad":
+					emit(" ", shift, true)
+				case len(name) == 1 && !unicode.IsLetter(rune(name[0])):
+					emit(name, shift, true)
+				case mathFunctions[name]:
+					emit(name, shift, true)
+				case mathSymbols[name] != "":
+					emit(mathSymbols[name], shift, !unicode.IsLower([]rune(mathSymbols[name])[0]))
+				default:
+					emit(name, shift, false)
+				}
+			case unicode.IsSpace(rune(c)):
+				i++
+			case strings.IndexByte("+-=<>", c) >= 0:
+				last := len(runs) - 1
+				unary := c == '-' && (last < 0 || strings.HasSuffix(runs[last].text, " ") || strings.HasSuffix(runs[last].text, "("))
+				if shift == 0 && !unary {
+					emit(" "+string(c)+" ", shift, true)
+				} else {
+					emit(string(c), shift, true)
+				}
+				i++
+			case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+				emit(string(c), shift, false)
+				i++
+			default:
+				r, size := utf8.DecodeRuneInString(s[i:])
+				emit(string(r), shift, true)
+				i += size
+			}
+		}
+	}
+	parse(latex, 0)
+	return runs
+}
+
+// mathCommand reads the LaTeX command starting with the backslash at i.
+//
+// Returns:
+//   - string: Command name: a run of letters, or a single other character
+//   - int: Index after the command
+func mathCommand(s string, i int) (string, int) {
+	end := i + 1
+	for end < len(s) && unicode.IsLetter(rune(s[end])) {
+		end++
+	}
+	if end == i+1 && end < len(s) {
+		end++
+	}
+	return s[i+1 : end], end
+}
+
+// mathArgument reads a command argument starting at i: a braced group, a
+// command, or a single character.
+//
+// Returns:
+//   - string: The argument, without braces for a group
+//   - int: Index after the argument
+func mathArgument(s string, i int) (string, int) {
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	if i >= len(s) {
+		return "", i
+	}
+	switch s[i] {
+	case '{':
+		depth := 0
+		for j := i; j < len(s); j++ {
+			switch s[j] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					return s[i+1 : j], j + 1
+				}
+			}
+		}
+		return s[i+1:], len(s)
+	case '\\':
+		_, end := mathCommand(s, i)
+		return s[i:end], end
+	}
+	_, size := utf8.DecodeRuneInString(s[i:])
+	return s[i : i+size], i + size
+}
+
+// mathGroup parenthesizes a fraction or root argument longer than one
+// character, so that "\frac{a+b}{2}" reads "(a+b)/2".
+func mathGroup(arg string) string {
+	if len([]rune(strings.TrimSpace(arg))) <= 1 {
+		return arg
+	}
+	return "(" + arg + ")"
+}
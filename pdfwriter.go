@@ -0,0 +1,101 @@
+package bookie
+
+import (
+	"io"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// pdfWriter is the subset of *gofpdf.Fpdf the compiler draws with. The
+// compiler holds its document through this interface, so that tests can
+// replace it with a recording implementation and check the calls that
+// rendering makes.
+type pdfWriter interface {
+	// Document setup and output
+	AddUTF8FontFromBytes(familyStr, styleStr string, utf8Bytes []byte)
+	AliasNbPages(aliasStr string)
+	OutputFileAndClose(fileStr string) error
+	RawWriteStr(str string)
+	SetCatalogSort(flag bool)
+	SetCompression(compress bool)
+	SetCreationDate(tm time.Time)
+	SetDisplayMode(zoomStr, layoutStr string)
+	SetError(err error)
+	SetFooterFunc(fnc func())
+	SetHeaderFuncMode(fnc func(), homeMode bool)
+	SetModificationDate(tm time.Time)
+	SetProtection(actionFlag byte, userPassStr, ownerPassStr string)
+	SetXmpMetadata(xmpStream []byte)
+
+	// Pages and margins
+	AddPage()
+	AddPageFormat(orientationStr string, size gofpdf.SizeType)
+	GetAutoPageBreak() (auto bool, margin float64)
+	GetConversionRatio() float64
+	GetMargins() (left, top, right, bottom float64)
+	GetPageSize() (width, height float64)
+	PageNo() int
+	PageSize(pageNum int) (wd, ht float64, unitStr string)
+	SetAutoPageBreak(auto bool, margin float64)
+	SetLeftMargin(margin float64)
+	SetMargins(left, top, right float64)
+	SetPage(pageNum int)
+	SetRightMargin(margin float64)
+
+	// Cursor position
+	GetX() float64
+	GetXY() (float64, float64)
+	GetY() float64
+	Ln(h float64)
+	SetX(x float64)
+	SetXY(x, y float64)
+	SetY(y float64)
+
+	// Fonts and text
+	Cell(w, h float64, txtStr string)
+	CellFormat(w, h float64, txtStr, borderStr string, ln int, alignStr string, fill bool, link int, linkStr string)
+	GetCellMargin() float64
+	GetFontSize() (ptSize, unitSize float64)
+	GetStringWidth(s string) float64
+	GetTextColor() (int, int, int)
+	MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill bool)
+	SetCellMargin(margin float64)
+	SetFont(familyStr, styleStr string, size float64)
+	SetFontStyle(styleStr string)
+	SetTextColor(r, g, b int)
+	Text(x, y float64, txtStr string)
+	Write(h float64, txtStr string)
+
+	// Links and bookmarks
+	AddLink() int
+	Bookmark(txtStr string, level int, y float64)
+	SetLink(link int, y float64, page int)
+	WriteLinkID(h float64, displayStr string, linkID int)
+	WriteLinkString(h float64, displayStr, targetStr string)
+
+	// Graphics and images
+	GetDrawColor() (int, int, int)
+	GetLineWidth() float64
+	Image(imageNameStr string, x, y, w, h float64, flow bool, tp string, link int, linkStr string)
+	Line(x1, y1, x2, y2 float64)
+	Rect(x, y, w, h float64, styleStr string)
+	RegisterImage(fileStr, tp string) *gofpdf.ImageInfoType
+	RegisterImageOptionsReader(imgName string, options gofpdf.ImageOptions, r io.Reader) *gofpdf.ImageInfoType
+	SetAlpha(alpha float64, blendModeStr string)
+	SetDrawColor(r, g, b int)
+	SetFillColor(r, g, b int)
+	SetLineWidth(width float64)
+	TransformBegin()
+	TransformEnd()
+	TransformRotate(angle, x, y float64)
+}
+
+// *gofpdf.Fpdf is the pdfWriter used when compiling.
+var _ pdfWriter = (*gofpdf.Fpdf)(nil)
+
+// newGofpdf creates the A4 *gofpdf.Fpdf document that books are written
+// to unless a test replaces BookCompiler.newPDF.
+func newGofpdf() pdfWriter {
+	return gofpdf.New(pdfOrientation, pdfUnit, pdfFormat, "")
+}
@@ -0,0 +1,253 @@
+package bookie
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// pdfCall is one call recorded by recordingPDF, with the page and the
+// drawing state it was made in.
+type pdfCall struct {
+	method    string
+	page      int
+	text      string  // Text drawn, or the image name
+	family    string  // Font family as passed to SetFont
+	style     string  // Font style as passed to SetFont or SetFontStyle
+	size      float64 // Font size in points
+	textColor [3]int
+	drawColor [3]int
+	lineWidth float64
+	level     int     // Outline level of a bookmark
+	x, y      float64 // Position of the call, or the start of a line
+	w, h      float64 // Size of an image or rectangle, or the end of a line
+}
+
+// recordingPDF is a pdfWriter that draws to a real *gofpdf.Fpdf, so that
+// layout behaves as in a compiled book, and records the pages, text,
+// images, and lines drawn through it.
+type recordingPDF struct {
+	*gofpdf.Fpdf
+	calls  []pdfCall
+	family string
+	style  string
+	size   float64
+}
+
+// newRecordingPDF creates a recordingPDF on the standard A4 document.
+func newRecordingPDF() *recordingPDF {
+	return &recordingPDF{Fpdf: newGofpdf().(*gofpdf.Fpdf)}
+}
+
+// record appends a call made in the current drawing state.
+func (r *recordingPDF) record(call pdfCall) {
+	call.page = r.PageNo()
+	call.family, call.style, call.size = r.family, r.style, r.size
+	tr, tg, tb := r.Fpdf.GetTextColor()
+	call.textColor = [3]int{tr, tg, tb}
+	dr, dg, db := r.Fpdf.GetDrawColor()
+	call.drawColor = [3]int{dr, dg, db}
+	call.lineWidth = r.Fpdf.GetLineWidth()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recordingPDF) SetFont(familyStr, styleStr string, size float64) {
+	r.family, r.style = familyStr, styleStr
+	if size > 0 {
+		r.size = size
+	}
+	r.Fpdf.SetFont(familyStr, styleStr, size)
+}
+
+func (r *recordingPDF) SetFontStyle(styleStr string) {
+	r.style = styleStr
+	r.Fpdf.SetFontStyle(styleStr)
+}
+
+func (r *recordingPDF) AddPage() {
+	r.Fpdf.AddPage()
+	r.record(pdfCall{method: "AddPage"})
+}
+
+func (r *recordingPDF) Cell(w, h float64, txtStr string) {
+	r.record(pdfCall{method: "Cell", text: txtStr, x: r.GetX(), y: r.GetY()})
+	r.Fpdf.Cell(w, h, txtStr)
+}
+
+func (r *recordingPDF) CellFormat(w, h float64, txtStr, borderStr string, ln int, alignStr string, fill bool, link int, linkStr string) {
+	r.record(pdfCall{method: "CellFormat", text: txtStr, x: r.GetX(), y: r.GetY()})
+	r.Fpdf.CellFormat(w, h, txtStr, borderStr, ln, alignStr, fill, link, linkStr)
+}
+
+func (r *recordingPDF) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill bool) {
+	r.record(pdfCall{method: "MultiCell", text: txtStr, x: r.GetX(), y: r.GetY()})
+	r.Fpdf.MultiCell(w, h, txtStr, borderStr, alignStr, fill)
+}
+
+func (r *recordingPDF) Text(x, y float64, txtStr string) {
+	r.record(pdfCall{method: "Text", text: txtStr, x: x, y: y})
+	r.Fpdf.Text(x, y, txtStr)
+}
+
+func (r *recordingPDF) Write(h float64, txtStr string) {
+	r.record(pdfCall{method: "Write", text: txtStr, x: r.GetX(), y: r.GetY()})
+	r.Fpdf.Write(h, txtStr)
+}
+
+func (r *recordingPDF) WriteLinkID(h float64, displayStr string, linkID int) {
+	r.record(pdfCall{method: "WriteLinkID", text: displayStr, x: r.GetX(), y: r.GetY()})
+	r.Fpdf.WriteLinkID(h, displayStr, linkID)
+}
+
+func (r *recordingPDF) WriteLinkString(h float64, displayStr, targetStr string) {
+	r.record(pdfCall{method: "WriteLinkString", text: displayStr, x: r.GetX(), y: r.GetY()})
+	r.Fpdf.WriteLinkString(h, displayStr, targetStr)
+}
+
+func (r *recordingPDF) Bookmark(txtStr string, level int, y float64) {
+	r.record(pdfCall{method: "Bookmark", text: txtStr, level: level, x: r.GetX(), y: r.GetY()})
+	r.Fpdf.Bookmark(txtStr, level, y)
+}
+
+func (r *recordingPDF) Image(imageNameStr string, x, y, w, h float64, flow bool, tp string, link int, linkStr string) {
+	r.record(pdfCall{method: "Image", text: imageNameStr, x: x, y: y, w: w, h: h})
+	r.Fpdf.Image(imageNameStr, x, y, w, h, flow, tp, link, linkStr)
+}
+
+func (r *recordingPDF) Line(x1, y1, x2, y2 float64) {
+	r.record(pdfCall{method: "Line", x: x1, y: y1, w: x2, h: y2})
+	r.Fpdf.Line(x1, y1, x2, y2)
+}
+
+func (r *recordingPDF) Rect(x, y, w, h float64, styleStr string) {
+	r.record(pdfCall{method: "Rect", x: x, y: y, w: w, h: h})
+	r.Fpdf.Rect(x, y, w, h, styleStr)
+}
+
+func (r *recordingPDF) OutputFileAndClose(fileStr string) error {
+	r.record(pdfCall{method: "OutputFileAndClose", text: fileStr})
+	return r.Fpdf.OutputFileAndClose(fileStr)
+}
+
+// textCalls returns the calls that drew text, in order.
+func (r *recordingPDF) textCalls() []pdfCall {
+	var calls []pdfCall
+	for _, call := range r.calls {
+		switch call.method {
+		case "Cell", "CellFormat", "MultiCell", "Text", "Write", "WriteLinkID", "WriteLinkString":
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// methodCalls returns the recorded calls of one method, in order.
+func (r *recordingPDF) methodCalls(method string) []pdfCall {
+	var calls []pdfCall
+	for _, call := range r.calls {
+		if call.method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// pageText returns the text drawn on a page, in drawing order.
+func (r *recordingPDF) pageText(page int) string {
+	var text strings.Builder
+	for _, call := range r.textCalls() {
+		if call.page == page {
+			text.WriteString(call.text)
+			text.WriteString(" ")
+		}
+	}
+	return text.String()
+}
+
+// pageOf returns the first page on which text containing s was drawn, or
+// 0 if it was not drawn.
+func (r *recordingPDF) pageOf(s string) int {
+	for _, call := range r.textCalls() {
+		if strings.Contains(call.text, s) {
+			return call.page
+		}
+	}
+	return 0
+}
+
+// writeBook creates a book directory from file contents keyed by their
+// slash-separated paths relative to the book root.
+func writeBook(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// newRecordedCompiler creates a compiler for the book in root whose
+// documents are recordingPDFs. The returned function gives the most
+// recently created document, which after Compile is the finished book.
+func newRecordedCompiler(t *testing.T, root string) (*BookCompiler, func() *recordingPDF) {
+	t.Helper()
+	bc := NewBookCompiler(root, filepath.Join(t.TempDir(), "book.pdf"))
+	var last *recordingPDF
+	bc.newPDF = func() pdfWriter {
+		last = newRecordingPDF()
+		return last
+	}
+	return bc, func() *recordingPDF { return last }
+}
+
+// compileRecorded compiles the book in root after applying configure, if
+// not nil, and returns the recording of the finished document.
+func compileRecorded(t *testing.T, root string, configure func(bc *BookCompiler)) (*BookCompiler, *recordingPDF) {
+	t.Helper()
+	bc, recording := newRecordedCompiler(t, root)
+	if configure != nil {
+		configure(bc)
+	}
+	if err := bc.Compile(); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return bc, recording()
+}
+
+func TestCompileCallSequence(t *testing.T) {
+	root := writeBook(t, map[string]string{
+		"Episode01/content.md": "Hello from the first chapter.\n",
+	})
+	_, pdf := compileRecorded(t, root, nil)
+
+	var sequence []string
+	for _, call := range pdf.calls {
+		switch {
+		case call.method == "AddPage":
+			sequence = append(sequence, "AddPage")
+		case call.method == "Bookmark":
+			// The chapter's bookmark repeats its title; only drawn text counts
+			continue
+		case call.text == "Contents", call.text == "Episode 01", strings.Contains(call.text, "Hello"):
+			sequence = append(sequence, call.text)
+		case call.method == "OutputFileAndClose":
+			sequence = append(sequence, "Output")
+		}
+	}
+	want := []string{"AddPage", "Contents", "AddPage", "Episode 01", "Hello from the first chapter.", "Output"}
+	if strings.Join(sequence, "|") != strings.Join(want, "|") {
+		t.Errorf("call sequence = %q, want %q", sequence, want)
+	}
+	if page := pdf.pageOf("Hello"); page != 2 {
+		t.Errorf("chapter text on page %d, want 2", page)
+	}
+}
@@ -0,0 +1,133 @@
+package bookie
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Remote image limits protect compilation from slow or oversized downloads.
+const (
+	remoteImageTimeout = 30 * time.Second // Maximum time for a single download
+	remoteImageMaxSize = 10 << 20         // Maximum image size in bytes (10 MiB)
+)
+
+// ErrRemoteImagesDisabled indicates an http(s) image was referenced while
+// remote images are not allowed.
+var ErrRemoteImagesDisabled = errors.New("remote images are disabled")
+
+// ErrUnsupportedRemoteImage indicates a remote image is in a format that
+// cannot be rendered.
+var ErrUnsupportedRemoteImage = errors.New("unsupported remote image format")
+
+// remoteImageExtensions maps the content types of renderable images to
+// file extensions.
+var remoteImageExtensions = map[string]string{
+	"image/jpeg":    jpgExtension,
+	"image/jpg":     jpgExtension,
+	"image/svg+xml": svgExtension,
+}
+
+// isRemoteURL reports whether an image source is an http or https URL.
+//
+// Parameters:
+//   - src: Image reference as written in the markdown source
+//
+// Returns:
+//   - bool: true if src uses the http or https scheme
+func isRemoteURL(src string) bool {
+	lower := strings.ToLower(src)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// fetchRemoteImage downloads a remote image to a temporary file.
+// Downloads are cached per URL for the duration of a compilation.
+//
+// Parameters:
+//   - url: http or https image URL
+//
+// Returns:
+//   - string: Path to the downloaded temporary file
+//   - error: Network, status, size, or unsupported format errors
+//
+// The file extension is derived from the response Content-Type, falling
+// back to the extension in the URL path. Images that are neither JPEG nor
+// SVG are rejected before their body is downloaded.
+func (bc *BookCompiler) fetchRemoteImage(url string) (string, error) {
+	if !bc.allowRemoteImages {
+		return "", fmt.Errorf("%w: %s", ErrRemoteImagesDisabled, url)
+	}
+	if cached, ok := bc.remoteImages[url]; ok {
+		return cached, nil
+	}
+
+	client := &http.Client{Timeout: remoteImageTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch image %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch image %s: %s", url, resp.Status)
+	}
+
+	ext := remoteImageExtension(url, resp)
+	if !isJPEGImage(ext) && !isSVGImage(ext) {
+		return "", fmt.Errorf("%w: %s (Content-Type %q)", ErrUnsupportedRemoteImage, url, resp.Header.Get("Content-Type"))
+	}
+
+	tmpFile, err := os.CreateTemp("", "bookie-remote-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary image file: %w", err)
+	}
+	defer tmpFile.Close()
+
+	n, err := io.Copy(tmpFile, io.LimitReader(resp.Body, remoteImageMaxSize+1))
+	if err == nil && n > remoteImageMaxSize {
+		err = fmt.Errorf("image exceeds %d byte limit", remoteImageMaxSize)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("failed to download image %s: %w", url, err)
+	}
+
+	bc.remoteImages[url] = tmpFile.Name()
+	return tmpFile.Name(), nil
+}
+
+// remoteImageExtension determines the file extension for a downloaded image.
+//
+// Parameters:
+//   - url: Image URL, used as a fallback source for the extension
+//   - resp: HTTP response carrying the Content-Type header
+//
+// Returns:
+//   - string: File extension including the leading dot, or empty if unknown
+func remoteImageExtension(url string, resp *http.Response) string {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err == nil {
+		if ext, ok := remoteImageExtensions[strings.ToLower(mediaType)]; ok {
+			return ext
+		}
+	}
+
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+	return strings.ToLower(path.Ext(url))
+}
+
+// removeRemoteImages deletes all temporary files created for remote images.
+func (bc *BookCompiler) removeRemoteImages() {
+	for url, file := range bc.remoteImages {
+		os.Remove(file)
+		delete(bc.remoteImages, url)
+	}
+}
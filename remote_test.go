@@ -0,0 +1,31 @@
+package bookie
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchRemoteImageRejectsUnsupportedFormat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	}))
+	defer server.Close()
+
+	bc := NewBookCompiler(t.TempDir(), "")
+	bc.SetAllowRemoteImages(true)
+	url := server.URL + "/figure"
+	path, err := bc.fetchRemoteImage(url)
+	if !errors.Is(err, ErrUnsupportedRemoteImage) {
+		t.Fatalf("fetchRemoteImage = %q, %v; want ErrUnsupportedRemoteImage", path, err)
+	}
+	if !strings.Contains(err.Error(), url) {
+		t.Errorf("error %q does not name the URL %s", err, url)
+	}
+	if len(bc.remoteImages) != 0 {
+		t.Errorf("cached %v for an unsupported image, want nothing", bc.remoteImages)
+	}
+}
@@ -3,12 +3,15 @@ package bookie
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
+	"strings"
 
 	"golang.org/x/net/html"
 )
 
+// attributionDash introduces a blockquote attribution line.
+const attributionDash = "- "
+
 // renderFormattingElement handles inline text formatting elements.
 // Supports emphasis (em/i), strong emphasis (strong/b), and underlining (u).
 //
@@ -19,22 +22,23 @@ import (
 //   - error: Any rendering errors encountered
 //
 // Supported styles:
-// - em/i: Italic text
+// - em/i/cite: Italic text
 // - strong/b: Bold text
 // - u: Underlined text
 //
-// Note: Formatting is automatically restored to normal after rendering.
+// Styles combine with the enclosing formatting (bold inside italic renders
+// bold italic) and the exact previous text state is restored afterwards.
 func (bc *BookCompiler) renderFormattingElement(n *html.Node) error {
 	switch n.Data {
-	case "em", "i":
-		bc.pdf.SetFont(bc.textFont, fontStyleItalic, defaultFontSize)
-		err := bc.renderChildren(n)
-		bc.pdf.SetFont(bc.textFont, fontStyleNormal, defaultFontSize)
-		return err
-	case "strong", "b":
-		bc.pdf.SetFont(bc.textFont, fontStyleBold, defaultFontSize)
+	case "em", "i", "cite", "strong", "b":
+		previous := bc.font
+		add := fontStyleItalic
+		if n.Data == "strong" || n.Data == "b" {
+			add = fontStyleBold
+		}
+		bc.setFont(previous.FontFamily, combineStyles(previous.Style, add), previous.Size)
 		err := bc.renderChildren(n)
-		bc.pdf.SetFont(bc.textFont, fontStyleNormal, defaultFontSize)
+		bc.restoreTextState(previous)
 		return err
 	case "u":
 		x := bc.pdf.GetX()
@@ -49,7 +53,8 @@ func (bc *BookCompiler) renderFormattingElement(n *html.Node) error {
 }
 
 // renderLink processes hyperlink elements with optional styling.
-// Links are rendered in blue to distinguish them from normal text.
+// External links are rendered as clickable text in the configured link
+// color, optionally underlined, pointing at the href.
 //
 // Parameters:
 //   - n: Anchor element node to render
@@ -58,16 +63,23 @@ func (bc *BookCompiler) renderFormattingElement(n *html.Node) error {
 //   - error: Any rendering errors encountered
 //
 // Features:
-// - Blue color for link text
+// - Configurable link color and underline
+// - Fragment hrefs ("#anchor") become internal jumps
 // - Preserves href attribute
-// - Restores text color after rendering
+// - Restores the previous text color after rendering
 // - Handles empty links gracefully
 func (bc *BookCompiler) renderLink(n *html.Node) error {
 	href := getAttr(n, "href")
+	if strings.HasPrefix(href, "#") {
+		return bc.renderInternalLink(n, strings.TrimPrefix(href, "#"))
+	}
 	if href != "" {
-		bc.pdf.SetTextColor(0, 0, 255) // Blue color for links
+		previous := bc.linkURL
+		bc.linkURL = href
+		previousColor := bc.setTextColor(bc.linkColor)
 		err := bc.renderChildren(n)
-		bc.pdf.SetTextColor(0, 0, 0) // Reset to black
+		bc.restoreTextColor(previousColor)
+		bc.linkURL = previous
 		return err
 	}
 	return bc.renderChildren(n)
@@ -88,41 +100,256 @@ func (bc *BookCompiler) renderLink(n *html.Node) error {
 // - Alt text support for accessibility
 // - Error handling for missing images
 //
-// Related: handleImage, Chapter
+// Related: handleImage, resolveImagePath, Chapter
 func (bc *BookCompiler) renderImage(n *html.Node) error {
 	src := getAttr(n, "src")
 	if src == "" {
 		return nil
 	}
 
-	imagePath := ""
+	imagePath, err := bc.resolveImagePath(src)
+	if err != nil {
+		return bc.unresolvedImage(src, err)
+	}
+	bc.checkAltText(n, src)
+
+	caption := bc.numberCaption(figureLabelPrefix, getAttr(n, "id"), getAttr(n, "alt"))
+	if err := bc.handleImage(imagePath, caption, getAttr(n, "alt")); err != nil {
+		return bc.unresolvedImage(src, err)
+	}
+	return nil
+}
+
+// unresolvedImage handles an image that could not be found or loaded.
+// With continue-on-error enabled, the image is recorded, a warning is
+// logged, and a placeholder box naming it is drawn; otherwise the error is
+// returned.
+//
+// Parameters:
+//   - src: Image source as written in the markdown
+//   - err: Resolution or loading error
+//
+// Returns:
+//   - error: err, or nil if compilation continues
+func (bc *BookCompiler) unresolvedImage(src string, err error) error {
+	if !bc.continueOnError {
+		return err
+	}
+	bc.unresolvedImages = append(bc.unresolvedImages, fmt.Sprintf("%s: %s", bc.currentFile, src))
+	bc.logWarning("Image %s in %s replaced by a placeholder: %v", src, bc.currentFile, err)
+
+	bc.pdf.Ln(defaultLineHeight)
+	if bc.spaceLeft() < placeholderHeight {
+		bc.pdf.AddPage()
+	}
+	x, y := bc.pdf.GetX(), bc.pdf.GetY()
+	drawR, drawG, drawB := bc.pdf.GetDrawColor()
+	bc.pdf.SetDrawColor(placeholderGray, placeholderGray, placeholderGray)
+	bc.pdf.Rect(x, y, imageWidth, placeholderHeight, "D")
+	bc.pdf.SetDrawColor(drawR, drawG, drawB)
+
+	previousColor := bc.setTextColor(rgbColor{placeholderGray, placeholderGray, placeholderGray})
+	if bc.font.FontFamily == "" {
+		// A cover placeholder can precede any text in the document
+		bc.setFont(bc.textFont, fontStyleNormal, defaultFontSize)
+	}
+	bc.pdf.SetXY(x, y)
+	bc.pdf.CellFormat(imageWidth, placeholderHeight, bc.cleanText("Missing image: "+src), "", 0, AlignCenter, false, 0, "")
+	bc.restoreTextColor(previousColor)
+	bc.pdf.SetXY(x, y+placeholderHeight+5)
+	return nil
+}
+
+// checkAltText records an image that has no alt text, logging a warning
+// when alt text warnings are enabled.
+//
+// Parameters:
+//   - img: Image element
+//   - src: Image source as written in the markdown
+func (bc *BookCompiler) checkAltText(img *html.Node, src string) {
+	if strings.TrimSpace(getAttr(img, "alt")) != "" {
+		return
+	}
+	bc.missingAltText = append(bc.missingAltText, fmt.Sprintf("%s: %s", bc.currentFile, src))
+	if bc.altTextWarnings {
+		bc.logWarning("Image %s in %s has no alt text", src, bc.currentFile)
+	}
+}
+
+// resolveImagePath locates the image file referenced by an img src attribute.
+//
+// Parameters:
+//   - src: Image reference as written in the markdown source
+//
+// Returns:
+//   - string: Path to the image file on disk
+//   - error: If the image cannot be found
+//
+// Remote http(s) URLs are downloaded when allowed. Otherwise the current
+// chapter's image map is consulted first, followed by the raw path, the
+// root directory, the chapter's source directory, and the directory of the
+// current file. Last, a src qualified by a directory (e.g., "figs/a.jpg")
+// is looked up in the chapter's image map by its file name, as images
+// found anywhere in the chapter directory are keyed by file name.
+func (bc *BookCompiler) resolveImagePath(src string) (string, error) {
+	if isRemoteURL(src) {
+		return bc.fetchRemoteImage(src)
+	}
+
 	// Try chapter-specific image mapping first
-	if chapter, ok := bc.currentChapter.(Chapter); ok && chapter.Images != nil {
-		if fullPath, exists := chapter.Images[src]; exists {
-			imagePath = fullPath
-		}
+	chapter, _ := bc.currentChapter.(Chapter)
+	if fullPath, exists := chapter.Images[src]; exists {
+		return fullPath, nil
 	}
 
 	// Fall back to path resolution if not found in chapter
-	if imagePath == "" {
-		possibilities := []string{
-			src,
-			filepath.Join(bc.RootDir, src),
-			filepath.Join(filepath.Dir(bc.currentFile), src),
+	possibilities := []string{
+		src,
+		filepath.Join(bc.RootDir, src),
+		filepath.Join(filepath.Dir(filepath.Dir(bc.currentFile)), src),
+		filepath.Join(filepath.Dir(bc.currentFile), src),
+	}
+	for _, path := range possibilities {
+		if _, err := bc.stat(path); err == nil {
+			return path, nil
 		}
-		for _, path := range possibilities {
-			if _, err := os.Stat(path); err == nil {
-				imagePath = path
-				break
-			}
+	}
+
+	if fullPath, exists := chapter.Images[filepath.Base(filepath.FromSlash(src))]; exists {
+		return fullPath, nil
+	}
+
+	return "", fmt.Errorf("image not found: %s", src)
+}
+
+// renderFigure handles figure elements wrapping an image and its caption.
+// The figcaption text is preferred over the image alt text when both exist.
+//
+// Parameters:
+//   - n: Figure element node to render
+//
+// Returns:
+//   - error: Image processing or path resolution errors
+//
+// Figures without an img descendant fall back to rendering their children.
+func (bc *BookCompiler) renderFigure(n *html.Node) error {
+	img := findDescendant(n, "img")
+	if img == nil {
+		return bc.renderChildren(n)
+	}
+
+	src := getAttr(img, "src")
+	if src == "" {
+		return nil
+	}
+
+	imagePath, err := bc.resolveImagePath(src)
+	if err != nil {
+		return bc.unresolvedImage(src, err)
+	}
+	bc.checkAltText(img, src)
+
+	caption := strings.TrimSpace(getTextContent(findDescendant(n, "figcaption")))
+	if caption == "" {
+		caption = getAttr(img, "alt")
+	}
+
+	id := getAttr(n, "id")
+	if id == "" {
+		id = getAttr(img, "id")
+	}
+
+	if err := bc.handleImage(imagePath, bc.numberCaption(figureLabelPrefix, id, caption), getAttr(img, "alt")); err != nil {
+		return bc.unresolvedImage(src, err)
+	}
+	return nil
+}
+
+// renderMark renders highlighted text (<mark>) over a background in the
+// configured highlight color.
+//
+// Parameters:
+//   - n: Mark element node to render
+//
+// Returns:
+//   - error: Any rendering errors encountered
+func (bc *BookCompiler) renderMark(n *html.Node) error {
+	previous := bc.highlighting
+	bc.highlighting = true
+	err := bc.renderChildren(n)
+	bc.highlighting = previous
+	return err
+}
+
+// renderDiv renders a div element. Markdown written inside the div is
+// converted first, since blackfriday passes HTML blocks through verbatim.
+//
+// Parameters:
+//   - n: Div element node
+//
+// Returns:
+//   - error: Any conversion or rendering errors encountered
+//
+// Recognized classes:
+// - keep-together: starts a new page if the content would not fit on the
+// current one
+// - note, tip, important, warning, caution: draws the content in a
+// callout box, titled by the div's title attribute if it has one
+//
+// Other classes are rendered transparently.
+func (bc *BookCompiler) renderDiv(n *html.Node) error {
+	if err := bc.expandMarkdown(n); err != nil {
+		return err
+	}
+	if hasClass(n, keepTogetherClass) {
+		bc.keepTogether(n)
+	}
+	if kind, ok := elementCallout(n); ok {
+		if title := getAttr(n, "title"); title != "" {
+			kind.title = title
 		}
+		return bc.renderCallout(n, kind)
 	}
+	return bc.renderChildren(n)
+}
 
-	if imagePath == "" {
-		return fmt.Errorf("image not found: %s", src)
+// renderSpan renders a span element. Math spans are rendered by
+// renderMath, and spans with a callout class (e.g., note or warning) are
+// drawn in the callout's color; other classes are rendered transparently.
+//
+// Parameters:
+//   - n: Span element node
+//
+// Returns:
+//   - error: Any rendering errors encountered
+func (bc *BookCompiler) renderSpan(n *html.Node) error {
+	if hasClass(n, mathClass) {
+		return bc.renderMath(n)
 	}
+	kind, ok := elementCallout(n)
+	if !ok {
+		return bc.renderChildren(n)
+	}
+	previousColor := bc.setTextColor(kind.color)
+	err := bc.renderChildren(n)
+	bc.restoreTextColor(previousColor)
+	return err
+}
 
-	return bc.handleImage(imagePath, getAttr(n, "alt"))
+// renderInlineCode renders code spans (<code> outside <pre>) in a
+// monospace font within the surrounding line, keeping its size.
+//
+// Parameters:
+//   - n: Code element node to render
+//
+// Returns:
+//   - error: Any rendering errors encountered
+func (bc *BookCompiler) renderInlineCode(n *html.Node) error {
+	previous := bc.font
+	bc.setFont(bc.styleFor("code", TextStyle{FontFamily: "Courier"}).FontFamily, fontStyleNormal, previous.Size)
+	err := bc.renderChildren(n)
+	bc.restoreTextState(previous)
+	return err
 }
 
 // renderBlockquote handles quoted text blocks with distinct styling.
@@ -135,19 +362,160 @@ func (bc *BookCompiler) renderImage(n *html.Node) error {
 //   - error: Any rendering errors encountered
 //
 // Features:
-// - Left margin indentation (20mm)
+// - Left margin indentation (20mm) for every line of the quote
 // - Italic text styling
+// - Vertical accent bar and optional background tint
 // - Proper spacing before and after
 // - Maintains original text alignment
 func (bc *BookCompiler) renderBlockquote(n *html.Node) error {
-	bc.pdf.SetX(bc.pdf.GetX() + 20)
-	bc.pdf.SetFont(bc.textFont, fontStyleItalic, defaultFontSize)
-	err := bc.renderChildren(n)
-	bc.pdf.SetX(bc.pdf.GetX() - 20)
+	left, _, right, _ := bc.pdf.GetMargins()
+	startPage, startY := bc.pdf.PageNo(), bc.pdf.GetY()
+	if first := firstElementChild(n); first != nil && first.Data == "p" {
+		startY += bc.paragraphSpacing
+	}
+
+	if bc.rtl() {
+		bc.pdf.SetRightMargin(right + blockquoteIndent)
+	} else {
+		bc.pdf.SetLeftMargin(left + blockquoteIndent)
+		bc.pdf.SetX(left + blockquoteIndent)
+	}
+	style := bc.styleFor("blockquote", TextStyle{FontFamily: bc.textFont, Style: fontStyleItalic, Size: defaultFontSize})
+	bc.setFont(style.FontFamily, style.Style, style.Size)
+	if color := bc.colorFor("blockquote"); color != nil {
+		defer bc.restoreTextColor(bc.setTextColor(*color))
+	}
+	err := bc.renderQuoteContent(n)
+	bc.pdf.SetLeftMargin(left)
+	bc.pdf.SetRightMargin(right)
+
+	bc.decorateBlockquote(startPage, startY, left)
 	bc.pdf.Ln(8)
 	return err
 }
 
+// renderQuoteContent renders the children of a blockquote, setting a
+// trailing attribution apart as a right-aligned line below the quote.
+//
+// Parameters:
+//   - n: Blockquote element node
+//
+// Returns:
+//   - error: Any rendering errors encountered
+//
+// An attribution is a <cite> element, or a paragraph that consists of a
+// <cite> or begins with a dash ("—", "–", or "--"), as the last element of
+// the quote.
+func (bc *BookCompiler) renderQuoteContent(n *html.Node) error {
+	var attribution *html.Node
+	for c := n.LastChild; c != nil; c = c.PrevSibling {
+		if c.Type == html.ElementNode {
+			if isAttribution(c) {
+				attribution = c
+			}
+			break
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c == attribution {
+			continue
+		}
+		if err := bc.renderNode(c); err != nil {
+			return err
+		}
+	}
+
+	if attribution != nil {
+		text := strings.TrimSpace(getTextContent(attribution))
+		text = strings.TrimLeft(text, "—–- ")
+		previous := bc.font
+		bc.setFont(bc.textFont, fontStyleNormal, captionFontSize)
+		bc.pdf.Ln(defaultLineHeight / 2)
+		bc.cellWithFallback(0, bc.lineHeight(), bc.normalizeText(attributionDash+text), 1, AlignRight, 0)
+		bc.restoreTextState(previous)
+	}
+	return nil
+}
+
+// isAttribution reports whether a blockquote child holds the quote's
+// source: a <cite> element, a paragraph containing only a <cite>, or a
+// paragraph that begins with a dash.
+//
+// Parameters:
+//   - n: Element child of a blockquote
+//
+// Returns:
+//   - bool: true if n is an attribution line
+func isAttribution(n *html.Node) bool {
+	if n.Data == "cite" {
+		return true
+	}
+	if n.Data != "p" {
+		return false
+	}
+	if only := firstElementChild(n); only != nil && only.Data == "cite" &&
+		strings.TrimSpace(getTextContent(n)) == strings.TrimSpace(getTextContent(only)) {
+		return true
+	}
+	text := strings.TrimSpace(getTextContent(n))
+	return strings.HasPrefix(text, "—") || strings.HasPrefix(text, "–") || strings.HasPrefix(text, "--")
+}
+
+// decorateBlockquote draws the accent bar and background tint of a
+// rendered blockquote on every page it spans. The tint is blended with
+// multiply so the text drawn earlier stays visible.
+//
+// Parameters:
+//   - startPage: Page on which the quote begins
+//   - startY: Top of the quote on its first page
+//   - left: Page left margin
+func (bc *BookCompiler) decorateBlockquote(startPage int, startY, left float64) {
+	if bc.blockquoteBarWidth <= 0 && bc.blockquoteBackground == nil {
+		return
+	}
+
+	endPage, endX, endY := bc.pdf.PageNo(), bc.pdf.GetX(), bc.pdf.GetY()
+	width, height := bc.pdf.GetPageSize()
+	_, top, right, _ := bc.pdf.GetMargins()
+	_, bottom := bc.pdf.GetAutoPageBreak()
+	x := left + blockquoteBarOffset
+	tintX, tintWidth := x, width-right-x
+	if bc.rtl() {
+		x = width - right - blockquoteBarOffset - bc.blockquoteBarWidth
+		tintX, tintWidth = left, x+bc.blockquoteBarWidth-left
+	}
+
+	for page := startPage; page <= endPage; page++ {
+		bc.pdf.SetPage(page)
+		y0, y1 := top, height-bottom
+		if page == startPage {
+			y0 = startY
+		}
+		if page == endPage {
+			y1 = endY
+		}
+		if y1 <= y0 {
+			continue
+		}
+
+		if bg := bc.blockquoteBackground; bg != nil {
+			bc.pdf.SetAlpha(1, "Multiply")
+			bc.pdf.SetFillColor(bg.r, bg.g, bg.b)
+			bc.pdf.Rect(tintX, y0, tintWidth, y1-y0, "F")
+			bc.pdf.SetAlpha(1, "Normal")
+		}
+		if bc.blockquoteBarWidth > 0 {
+			bar := bc.blockquoteBarColor
+			bc.pdf.SetFillColor(bar.r, bar.g, bar.b)
+			bc.pdf.Rect(x, y0, bc.blockquoteBarWidth, y1-y0, "F")
+		}
+	}
+
+	bc.pdf.SetPage(endPage)
+	bc.pdf.SetXY(endX, endY)
+}
+
 // renderCode handles preformatted and code block elements.
 // Uses monospace font and preserves whitespace formatting.
 //
@@ -163,9 +531,13 @@ func (bc *BookCompiler) renderBlockquote(n *html.Node) error {
 // - Consistent spacing around blocks
 // - Automatic font restoration
 func (bc *BookCompiler) renderCode(n *html.Node) error {
-	bc.pdf.SetFont("Courier", fontStyleNormal, 10)
+	style := bc.styleFor("code", TextStyle{FontFamily: "Courier", Size: 10})
+	bc.setFont(style.FontFamily, style.Style, style.Size)
+	if color := bc.colorFor("code"); color != nil {
+		defer bc.restoreTextColor(bc.setTextColor(*color))
+	}
 	err := bc.renderChildren(n)
-	bc.pdf.SetFont(bc.textFont, fontStyleNormal, defaultFontSize)
+	bc.setFont(bc.textFont, fontStyleNormal, defaultFontSize)
 	bc.pdf.Ln(8)
 	return err
 }
@@ -191,7 +563,7 @@ func (bc *BookCompiler) needsSpacing(n *html.Node) bool {
 	}
 	spacingElements := map[string]bool{
 		"h1": true, "h2": true, "h3": true,
-		"p": true, "ul": true, "ol": true,
+		"ul": true, "ol": true,
 		"table": true, "blockquote": true,
 	}
 	return spacingElements[n.Data]
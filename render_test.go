@@ -0,0 +1,47 @@
+package bookie
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestImageSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		image    string // Path of the image file relative to the book root
+	}{
+		{
+			name:     "reference-style",
+			markdown: "![A figure][fig]\n\n[fig]: figs/a.jpg\n",
+			image:    "Episode01/figs/a.jpg",
+		},
+		{
+			name:     "reference-style by file name",
+			markdown: "![A figure][fig]\n\n[fig]: figs/a.jpg\n",
+			image:    "Episode01/images/a.jpg",
+		},
+		{
+			name:     "directory-qualified by file name",
+			markdown: "![A figure](figs/a.jpg)\n",
+			image:    "Episode01/images/a.jpg",
+		},
+	}
+	for _, tt := range tests {
+		root := writeBook(t, map[string]string{"Episode01/content.md": tt.markdown})
+		writeJPEG(t, filepath.Join(root, filepath.FromSlash(tt.image)))
+		bc, pdf := compileRecorded(t, root, nil)
+
+		images := pdf.methodCalls("Image")
+		if len(images) != 1 {
+			t.Errorf("%s: %d images drawn, want 1", tt.name, len(images))
+			continue
+		}
+		if want := filepath.Join(root, filepath.FromSlash(tt.image)); images[0].text != want {
+			t.Errorf("%s: drew %s, want %s", tt.name, images[0].text, want)
+		}
+		if len(bc.unresolvedImages) > 0 {
+			t.Errorf("%s: unresolved images %v", tt.name, bc.unresolvedImages)
+		}
+	}
+}
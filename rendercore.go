@@ -4,18 +4,25 @@ package bookie
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
+	"unicode"
 
 	"golang.org/x/net/html"
 )
 
+// wordToken splits text into words and the whitespace between them.
+var wordToken = regexp.MustCompile(`\S+|\s+`)
+
 // Layout constants define dimensions and spacing for PDF elements.
 // All measurements are in millimeters unless specified otherwise.
 const (
-	defaultLineHeight = 5.0   // Vertical spacing between lines
-	defaultFontSize   = 12.0  // Base font size in points
-	indentWidth       = 10.0  // List and blockquote indentation
-	pageWidth         = 190.0 // Available content width (A4 minus margins)
+	defaultLineHeight   = 5.0  // Vertical spacing between lines
+	defaultFontSize     = 12.0 // Base font size in points
+	indentWidth         = 10.0 // List indentation
+	blockquoteIndent    = 20.0 // Left indentation of blockquote text
+	blockquoteBarOffset = 10.0 // Distance of the blockquote bar from the left margin
+	captionFontSize     = 10.0 // Font size for figure captions in points
 )
 
 // Font style constants define standard text formatting options.
@@ -58,11 +65,19 @@ func (bc *BookCompiler) renderNode(n *html.Node) error {
 		return nil
 	}
 
+	if bc.pageBreakPending && n.Type != html.CommentNode && !isWhitespaceText(n) {
+		bc.pageBreakPending = false
+		bc.pdf.AddPage()
+	}
 	if bc.needsSpacing(n) {
 		bc.pdf.Ln(defaultLineHeight)
 	}
 
-	return bc.renderHTML(n)
+	err := bc.renderHTML(n)
+	if n.Type != html.CommentNode && !isWhitespaceText(n) {
+		bc.chapterOpening = false
+	}
+	return err
 }
 
 // renderChildren processes all direct child nodes of the given HTML node.
@@ -106,6 +121,11 @@ func (bc *BookCompiler) renderHTML(n *html.Node) error {
 		Size:       defaultFontSize,
 		Alignment:  "L",
 	}
+	if isInline(n) && bc.font.FontFamily != "" {
+		// Inline content returns to the enclosing style, so nested
+		// formatting keeps the outer emphasis
+		currentState = bc.font
+	}
 	defer bc.restoreTextState(currentState)
 
 	switch n.Type {
@@ -113,6 +133,13 @@ func (bc *BookCompiler) renderHTML(n *html.Node) error {
 		return bc.renderTextNode(n)
 	case html.ElementNode:
 		return bc.renderElement(n)
+	case html.CommentNode:
+		if isPageBreakMarker(n) {
+			bc.renderPageBreak()
+			return nil
+		}
+		bc.recordIndexTerm(n)
+		return nil
 	}
 
 	return bc.renderSiblings(n)
@@ -127,18 +154,236 @@ func (bc *BookCompiler) renderHTML(n *html.Node) error {
 // Returns:
 //   - error: Any writing errors encountered
 //
-// Empty or whitespace-only text is skipped.
+// Whitespace is trimmed at block boundaries but kept next to inline
+// elements; empty text is skipped. When single newlines are
+// preserved, each newline inside a paragraph becomes a line break.
 func (bc *BookCompiler) renderTextNode(n *html.Node) error {
-	text := bc.cleanText(n.Data)
-	if strings.TrimSpace(text) != "" {
-		bc.pdf.Write(defaultLineHeight, text)
+	text := trimBlockEdges(n)
+	if bc.preserveNewlines && strings.Contains(text, "\n") && findParent(n, "p") != nil {
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			bc.writeText(line)
+			if i < len(lines)-1 {
+				bc.pdf.Ln(bc.lineHeight())
+			}
+		}
+		return nil
 	}
+
+	bc.writeText(text)
 	return nil
 }
 
+// trimBlockEdges returns the text of a text node with whitespace removed
+// at block boundaries and after line breaks. Whitespace next to an inline
+// sibling (e.g., "see the " before <strong>) is kept so words do not run
+// together.
+//
+// Parameters:
+//   - n: Text node
+//
+// Returns:
+//   - string: Text with leading and trailing block-edge whitespace removed
+func trimBlockEdges(n *html.Node) string {
+	text := n.Data
+	if prev := n.PrevSibling; !isInline(prev) || prev.Type == html.ElementNode && prev.Data == "br" {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+	}
+	if !isInline(n.NextSibling) {
+		text = strings.TrimRightFunc(text, unicode.IsSpace)
+	}
+	return text
+}
+
+// writeText cleans raw text and writes it at the current position.
+// Empty text is skipped; a lone space between inline elements is kept.
+//
+// Parameters:
+//   - raw: Unprocessed text content
+func (bc *BookCompiler) writeText(raw string) {
+	bc.writeWithFallback(bc.normalizeText(raw))
+}
+
+// write writes prepared text at the current position, as a clickable
+// internal or external link when one is active. Text beside a drop cap,
+// inside a highlight, or with hyphenation on is wrapped word by word.
+//
+// Parameters:
+//   - text: Text ready for output in the current font
+func (bc *BookCompiler) write(text string) {
+	if bc.dropCapBottom > 0 || bc.highlighting || bc.activeHyphenator() != nil {
+		bc.writeWords(text)
+		return
+	}
+	bc.writeRun(text)
+}
+
+// writeWords writes text word by word, breaking lines itself. It is used
+// beside a drop cap, so that the first line below the drop cap returns to
+// the normal left margin, for highlighted text, whose background is
+// filled behind each word before it is drawn, and with hyphenation on, so
+// that a word overflowing the line can be split at a hyphenation point.
+//
+// Parameters:
+//   - text: Text ready for output in the current font
+func (bc *BookCompiler) writeWords(text string) {
+	width, _ := bc.pdf.GetPageSize()
+	_, _, right, _ := bc.pdf.GetMargins()
+	edge := width - right
+
+	for _, token := range wordToken.FindAllString(text, -1) {
+		fits := bc.pdf.GetX()+bc.pdf.GetStringWidth(token) <= edge
+		if strings.TrimSpace(token) == "" && !fits {
+			continue
+		}
+		for broken := false; !fits; broken = true {
+			// Write insets text by the cell margin on both sides
+			available := edge - bc.pdf.GetX() - 2*bc.pdf.GetCellMargin()
+			head, tail, ok := bc.hyphenateToFit(token, available)
+			if !ok && broken {
+				break
+			}
+			if ok {
+				bc.writeWord(head)
+				token = tail
+			}
+			bc.breakWordLine()
+			fits = !ok || bc.pdf.GetX()+bc.pdf.GetStringWidth(token) <= edge
+		}
+		bc.writeWord(token)
+	}
+}
+
+// breakWordLine starts a new line for writeWords, returning to the normal
+// left margin once the line is below a drop cap.
+func (bc *BookCompiler) breakWordLine() {
+	if bc.dropCapBottom > 0 && bc.pdf.GetY()+bc.lineHeight() >= bc.dropCapBottom {
+		bc.pdf.SetLeftMargin(bc.dropCapMargin)
+		bc.dropCapBottom = 0
+	}
+	bc.pdf.Ln(bc.lineHeight())
+}
+
+// writeWord writes one token for writeWords, filling the highlight
+// behind it when highlighting.
+//
+// Parameters:
+//   - token: Word or space ready for output in the current font
+func (bc *BookCompiler) writeWord(token string) {
+	if bc.highlighting {
+		bc.pdf.SetFillColor(bc.highlightColor.r, bc.highlightColor.g, bc.highlightColor.b)
+		// Write draws text offset by the cell margin
+		x := bc.pdf.GetX() + bc.pdf.GetCellMargin()
+		bc.pdf.Rect(x, bc.pdf.GetY(), bc.pdf.GetStringWidth(token), bc.lineHeight(), "F")
+	}
+	bc.writeRun(token)
+}
+
+// writeRun writes text with gofpdf's own line wrapping, as a link when
+// one is active.
+//
+// Parameters:
+//   - text: Text ready for output in the current font
+func (bc *BookCompiler) writeRun(text string) {
+	switch {
+	case bc.linkID != 0:
+		bc.pdf.WriteLinkID(bc.lineHeight(), text, bc.linkID)
+	case bc.linkURL != "" && bc.linkUnderline:
+		bc.pdf.SetFontStyle(bc.font.Style + "U")
+		bc.pdf.WriteLinkString(bc.lineHeight(), text, bc.linkURL)
+		bc.pdf.SetFontStyle(bc.font.Style)
+	case bc.linkURL != "":
+		bc.pdf.WriteLinkString(bc.lineHeight(), text, bc.linkURL)
+	default:
+		bc.pdf.Write(bc.lineHeight(), text)
+	}
+}
+
+// writeWithFallback writes text, switching to the glyph fallback font or
+// the built-in symbol font for each run of runes the current core font
+// cannot render. Runes no font can render are dropped with a warning.
+//
+// Parameters:
+//   - text: Normalized text to write
+func (bc *BookCompiler) writeWithFallback(text string) {
+	for _, run := range bc.glyphRuns(text) {
+		if run.family == "" {
+			bc.write(run.text)
+			continue
+		}
+		bc.setRunFont(run.family)
+		bc.write(run.text)
+		bc.setRunFont("")
+	}
+}
+
+// cellWithFallback draws a single-line cell like CellFormat, switching to
+// the glyph fallback font or the built-in symbol font for runs of runes
+// the current core font cannot render. Text the core font renders in
+// full is drawn with a single CellFormat call.
+//
+// Parameters:
+//   - w: Cell width in millimeters, or 0 to extend to the right margin
+//   - h: Cell height in millimeters
+//   - text: Normalized text to draw
+//   - ln: Position after the cell, as for CellFormat
+//   - align: AlignLeft, AlignCenter, or AlignRight
+//   - link: Internal link the cell points to, or 0
+func (bc *BookCompiler) cellWithFallback(w, h float64, text string, ln int, align string, link int) {
+	runs := bc.glyphRuns(text)
+	if len(runs) == 0 || len(runs) == 1 && runs[0].family == "" {
+		text = ""
+		if len(runs) == 1 {
+			text = runs[0].text
+		}
+		bc.pdf.CellFormat(w, h, text, "", ln, align, false, link, "")
+		return
+	}
+
+	x, y := bc.pdf.GetXY()
+	if w == 0 {
+		pageWidth, _ := bc.pdf.GetPageSize()
+		_, _, right, _ := bc.pdf.GetMargins()
+		w = pageWidth - right - x
+	}
+	widths := make([]float64, len(runs))
+	total := 0.0
+	for i, run := range runs {
+		bc.setRunFont(run.family)
+		widths[i] = bc.pdf.GetStringWidth(run.text)
+		total += widths[i]
+	}
+
+	margin := bc.pdf.GetCellMargin()
+	switch align {
+	case AlignCenter:
+		bc.pdf.SetX(x + (w-total)/2)
+	case AlignRight:
+		bc.pdf.SetX(x + w - margin - total)
+	default:
+		bc.pdf.SetX(x + margin)
+	}
+	bc.pdf.SetCellMargin(0)
+	for i, run := range runs {
+		bc.setRunFont(run.family)
+		bc.pdf.CellFormat(widths[i], h, run.text, "", 0, AlignLeft, false, link, "")
+	}
+	bc.pdf.SetCellMargin(margin)
+	bc.setRunFont("")
+
+	switch ln {
+	case 0:
+		bc.pdf.SetX(x + w)
+	case 1:
+		bc.pdf.Ln(h)
+	default:
+		bc.pdf.SetXY(x, y+h)
+	}
+}
+
 // renderElement dispatches HTML elements to appropriate handlers.
 // It supports headings, block elements, lists, formatting, tables,
-// links, images, and horizontal rules.
+// links, images, figures, and horizontal rules.
 //
 // Parameters:
 //   - n: Element node to render
@@ -146,16 +391,27 @@ func (bc *BookCompiler) renderTextNode(n *html.Node) error {
 // Returns:
 //   - error: Any rendering errors encountered
 //
+// Elements with an id attribute are registered as internal link targets.
 // Elements without specific handlers are ignored.
 func (bc *BookCompiler) renderElement(n *html.Node) error {
+	// Headings register their own anchor after any page break
+	if id := getAttr(n, "id"); id != "" && !isHeading(n) {
+		bc.registerAnchor(id)
+	}
+
 	switch n.Data {
 	case "h1", "h2", "h3", "h4", "h5", "h6":
 		return bc.renderHeading(n)
-	case "p", "blockquote", "pre", "code":
+	case "code":
+		if n.Parent == nil || n.Parent.Data != "pre" {
+			return bc.renderInlineCode(n)
+		}
+		return bc.renderBlockElement(n)
+	case "p", "blockquote", "pre":
 		return bc.renderBlockElement(n)
 	case "ul", "ol", "li":
 		return bc.renderListElement(n)
-	case "em", "i", "strong", "b", "u":
+	case "em", "i", "cite", "strong", "b", "u":
 		return bc.renderFormattingElement(n)
 	case "table":
 		return bc.renderTable(n)
@@ -163,6 +419,16 @@ func (bc *BookCompiler) renderElement(n *html.Node) error {
 		return bc.renderLink(n)
 	case "img":
 		return bc.renderImage(n)
+	case "figure":
+		return bc.renderFigure(n)
+	case "mark":
+		return bc.renderMark(n)
+	case "div":
+		return bc.renderDiv(n)
+	case "span":
+		return bc.renderSpan(n)
+	case "br":
+		bc.pdf.Ln(bc.lineHeight())
 	case "hr":
 		return bc.renderHorizontalRule()
 	}
@@ -0,0 +1,183 @@
+package bookie
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gofpdfFont returns the path of a font file shipped with the gofpdf
+// module, skipping the test if the module source cannot be found.
+func gofpdfFont(t *testing.T, name string) string {
+	t.Helper()
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "github.com/jung-kurt/gofpdf").Output()
+	if err != nil {
+		t.Skipf("gofpdf module source not found: %v", err)
+	}
+	path := filepath.Join(strings.TrimSpace(string(out)), "font", name)
+	if _, err := os.Stat(path); err != nil {
+		t.Skipf("font %s not found: %v", name, err)
+	}
+	return path
+}
+
+func TestGlyphFallbackFont(t *testing.T) {
+	bc, recording := newRecordedCompiler(t, t.TempDir())
+	bc.SetGlyphFallbackFont("fallback", gofpdfFont(t, "DejaVuSansCondensed.ttf"))
+	renderRecorded(t, bc, nil, "Resistance in Ω units.\n")
+
+	var omega, plain []pdfCall
+	for _, call := range recording().textCalls() {
+		switch {
+		case strings.Contains(call.text, "Ω"):
+			omega = append(omega, call)
+		case strings.Contains(call.text, "Resistance"):
+			plain = append(plain, call)
+		}
+	}
+	if len(omega) == 0 || len(plain) == 0 {
+		t.Fatalf("drew %d runs with the missing rune and %d with plain text, want both", len(omega), len(plain))
+	}
+	for _, call := range omega {
+		if call.family != "fallback" || strings.Contains(call.text, "Resistance") {
+			t.Errorf("%q drawn in %q, want the rune alone in the fallback font", call.text, call.family)
+		}
+	}
+	if family := plain[0].family; family != bc.textFont {
+		t.Errorf("plain text drawn in %q, want the primary font %q", family, bc.textFont)
+	}
+}
+
+// textY returns the vertical position at which text containing s was
+// first drawn, or -1 if it was not drawn.
+func textY(pdf *recordingPDF, s string) float64 {
+	for _, call := range pdf.textCalls() {
+		if strings.Contains(call.text, s) {
+			return call.y
+		}
+	}
+	return -1
+}
+
+func TestPreserveSingleNewlines(t *testing.T) {
+	const paragraph = "First line\nSecond line\nThird line\n"
+
+	bc, recording := newRecordedCompiler(t, t.TempDir())
+	bc.SetPreserveSingleNewlines(true)
+	renderRecorded(t, bc, nil, paragraph)
+	pdf := recording()
+	first, second, third := textY(pdf, "First"), textY(pdf, "Second"), textY(pdf, "Third")
+	if first < 0 || !(first < second && second < third) {
+		t.Errorf("lines drawn at y %.1f, %.1f, %.1f, want each below the one before", first, second, third)
+	}
+
+	bc, recording = newRecordedCompiler(t, t.TempDir())
+	renderRecorded(t, bc, nil, paragraph)
+	pdf = recording()
+	if first, third := textY(pdf, "First"), textY(pdf, "Third"); first != third {
+		t.Errorf("lines drawn at y %.1f and %.1f when disabled, want one line", first, third)
+	}
+}
+
+// drawnText returns all text drawn, concatenated in drawing order.
+func drawnText(pdf *recordingPDF) string {
+	var text strings.Builder
+	for _, call := range pdf.textCalls() {
+		text.WriteString(call.text)
+	}
+	return text.String()
+}
+
+func TestInlineElementSpacing(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{"link", "see [the link](https://example.com) here", "see the link here"},
+		{"bold", "word **bold** word", "word bold word"},
+		{"bold html", "word <b>bold</b> word", "word bold word"},
+		{"code", "use `go test` now", "use go test now"},
+		{"collapsed runs", "many   spaces\nand  newline", "many spaces and newline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, recording := newRecordedCompiler(t, t.TempDir())
+			renderRecorded(t, bc, nil, tt.markdown+"\n")
+			if got := strings.TrimSpace(drawnText(recording())); got != tt.want {
+				t.Errorf("drew %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLEntities(t *testing.T) {
+	tests := []struct {
+		markdown string
+		want     string
+	}{
+		{"Salt &amp; pepper", "Salt & pepper"},
+		{"before &mdash; after", "before - after"},
+		{"before &#8212; after", "before - after"},
+		{"a &lt;tag&gt; here", "a <tag> here"},
+	}
+	for _, tt := range tests {
+		bc, recording := newRecordedCompiler(t, t.TempDir())
+		renderRecorded(t, bc, nil, tt.markdown+"\n")
+		if got := strings.TrimSpace(drawnText(recording())); got != tt.want {
+			t.Errorf("%q drew %q, want %q", tt.markdown, got, tt.want)
+		}
+	}
+}
+
+func TestSmartPunctuation(t *testing.T) {
+	const markdown = "\"Quoted\" text -- it's here --- &mdash; done\n"
+
+	bc, recording := newRecordedCompiler(t, t.TempDir())
+	bc.SetSmartPunctuation(true)
+	renderRecorded(t, bc, nil, markdown)
+	if got, want := strings.TrimSpace(drawnText(recording())), `"Quoted" text - it's here - - done`; got != want {
+		t.Errorf("core fonts drew %q, want ASCII %q", got, want)
+	}
+
+	bc, recording = newRecordedCompiler(t, t.TempDir())
+	bc.SetSmartPunctuation(true)
+	bc.SetGlyphFallbackFont("fallback", gofpdfFont(t, "DejaVuSansCondensed.ttf"))
+	renderRecorded(t, bc, nil, markdown)
+	if got, want := strings.TrimSpace(drawnText(recording())), "“Quoted” text – it’s here — — done"; got != want {
+		t.Errorf("fallback font drew %q, want %q", got, want)
+	}
+}
+
+func TestSymbolsOutsideBodyText(t *testing.T) {
+	root := writeBook(t, map[string]string{
+		"Episode01/content.md": "---\ntitle: Checks ✓\n---\n\n![Figure ★](a.jpg)\n\n:::note Next → step\nBody text.\n:::\n",
+	})
+	writeJPEG(t, filepath.Join(root, "Episode01", "a.jpg"))
+	_, pdf := compileRecorded(t, root, nil)
+
+	// Contents on page 1 and the chapter on page 2
+	tests := []struct {
+		where string
+		page  int
+		glyph byte
+	}{
+		{where: "ToC entry", page: 1, glyph: symbolGlyphs['✓']},
+		{where: "chapter title", page: 2, glyph: symbolGlyphs['✓']},
+		{where: "caption", page: 2, glyph: symbolGlyphs['★']},
+		{where: "callout title", page: 2, glyph: symbolGlyphs['→']},
+	}
+	for _, tt := range tests {
+		found := false
+		for _, call := range pdf.textCalls() {
+			if call.page == tt.page && call.family == symbolFont && strings.Contains(call.text, string([]byte{tt.glyph})) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: symbol %#x not drawn in %s on page %d", tt.where, tt.glyph, symbolFont, tt.page)
+		}
+	}
+}
@@ -2,10 +2,37 @@ package bookie
 
 import (
 	"fmt"
+	"strings"
 
 	"golang.org/x/net/html"
 )
 
+// sceneBreakText is the ornament drawn for ThematicBreakAsterisks.
+const sceneBreakText = "* * *"
+
+// imageWidth is the display width of images in the text, in millimeters.
+const imageWidth = 100.0
+
+// Placeholder drawn for an image that cannot be found or loaded.
+const (
+	placeholderHeight = 30.0 // Height in millimeters; the width is imageWidth
+	placeholderGray   = 150  // Gray level of the border and text
+)
+
+// Manual page break markers: an HTML comment (<!-- pagebreak -->) or a
+// paragraph consisting of the token.
+const (
+	pageBreakComment = "pagebreak"
+	pageBreakToken   = `\pagebreak`
+)
+
+// Widow and orphan control thresholds, in lines.
+const (
+	minOrphanLines      = 2 // Fewest paragraph lines allowed at a page bottom
+	minWidowLines       = 2 // Fewest paragraph lines allowed at a page top
+	shortParagraphLines = 3 // Paragraphs this short are never split
+)
+
 // getPageHeight returns the current PDF page height in millimeters.
 // Used for pagination and layout calculations.
 //
@@ -18,6 +45,60 @@ func (bc *BookCompiler) getPageHeight() float64 {
 	return height
 }
 
+// spaceLeft returns the space between the cursor and the bottom margin of
+// the current page.
+//
+// Returns:
+//   - float64: Space in millimeters; negative below the margin
+func (bc *BookCompiler) spaceLeft() float64 {
+	_, bottom := bc.pdf.GetAutoPageBreak()
+	return bc.getPageHeight() - bottom - bc.pdf.GetY()
+}
+
+// blockBreakThreshold returns the space a block needs above the bottom
+// margin to start on the current page: the configured threshold, or the
+// block's space before plus its first line.
+//
+// Parameters:
+//   - before: Space above the block in millimeters
+//
+// Returns:
+//   - float64: Threshold in millimeters
+func (bc *BookCompiler) blockBreakThreshold(before float64) float64 {
+	if bc.pageBreakThreshold > 0 {
+		return bc.pageBreakThreshold
+	}
+	return before + bc.lineHeight()
+}
+
+// breakBeforeBlock starts a new page when a block with the given space
+// above it would not fit its first line on the current page.
+//
+// Parameters:
+//   - before: Space above the block in millimeters
+func (bc *BookCompiler) breakBeforeBlock(before float64) {
+	if bc.spaceLeft() < bc.blockBreakThreshold(before) {
+		bc.pdf.AddPage()
+	}
+}
+
+// lineHeight returns the body text line height: defaultLineHeight scaled
+// by the configured line spacing factor.
+//
+// Returns:
+//   - float64: Line height in millimeters
+//
+// Applies to all text written inline (paragraphs, blockquotes, code
+// blocks, list items, heading text) and captions. Table cells use
+// tableLineHeight scaled to the table font size; the spacing around
+// headings, the table of contents, and the index stay fixed.
+func (bc *BookCompiler) lineHeight() float64 {
+	if bc.cellLineHeight > 0 {
+		return bc.cellLineHeight
+	}
+	return defaultLineHeight * bc.lineSpacing
+}
+
 // renderHeading handles heading elements (h1-h6) with appropriate styling.
 // It manages page breaks and spacing for different heading levels.
 //
@@ -27,38 +108,89 @@ func (bc *BookCompiler) getPageHeight() float64 {
 // Returns:
 //   - error: Any rendering errors encountered
 //
-// Heading levels affect font size, spacing, and page breaks:
-// - h1: New page, 24pt
+// Headings are drawn in the configured heading color, using the per-level
+// font and spacing set with SetHeadingStyle. The defaults are:
+// - h1: 24pt, starts a new page by default (see SetPageBreakBeforeH1)
 // - h2: 20pt with extra spacing
 // - h3: 16pt with moderate spacing
 // - h4-h6: 14pt with minimal spacing
+//
+// Forced breaks (see SetPageBreakHeadingLevel) are skipped on a page that
+// is still empty and for a heading that opens a chapter, which stays below
+// the chapter title, so no blank pages are produced. Other headings move to
+// the next page unless the configured number of following body lines also
+// fits (see SetKeepWithNext). A heading's id is registered as a link
+// destination for cross-references and the table of contents. Headings
+// below h1 are bookmarked in the PDF outline under their chapter, and
+// those in appendices are numbered after the appendix letter.
 func (bc *BookCompiler) renderHeading(n *html.Node) error {
-	if bc.pdf.GetY() > bc.getPageHeight()-100 {
-		bc.pdf.AddPage()
+	level := int(n.Data[1] - '0')
+	style := bc.styleForHeading(level)
+	bc.dropCapPending = false
+	family := style.text.FontFamily
+	if family == "" {
+		family = bc.chapterFont
 	}
 
-	switch n.Data {
-	case "h1":
+	headingHeight := style.spaceBefore + style.text.Size/bc.pdf.GetConversionRatio()
+	_, top, _, _ := bc.pdf.GetMargins()
+	forceBreak := bc.pageBreakBefore[level] && bc.pdf.GetY() > top && !bc.chapterOpening
+	if forceBreak || !bc.fitsWithNext(headingHeight, style.spaceAfter) {
 		bc.pdf.AddPage()
-		bc.setHeadingStyle(24, 20)
-	case "h2":
-		bc.pdf.Ln(20)
-		bc.setHeadingStyle(20, 15)
-	case "h3":
-		bc.pdf.Ln(15)
-		bc.setHeadingStyle(16, 10)
-	default: // h4, h5, h6
-		bc.pdf.Ln(10)
-		bc.setHeadingStyle(14, 8)
-	}
-
-	if err := bc.renderChildren(n); err != nil {
+	}
+	bc.setFont(family, style.text.Style, style.text.Size)
+	bc.pdf.Ln(style.spaceBefore)
+	if id := getAttr(n, "id"); id != "" {
+		bc.registerAnchor(id)
+	}
+	prefix := ""
+	if number := bc.appendixHeadingNumber(level); number != "" {
+		prefix = number + " "
+	}
+	if level > 1 {
+		bc.addBookmark(prefix+getTextContent(n), level-1)
+	}
+	if !bc.rtl() {
+		bc.alignBlock(n, style.text.Alignment)
+	}
+
+	color := bc.headingColor
+	if styleColor := bc.colorFor(n.Data); styleColor != nil {
+		color = *styleColor
+	}
+	previousColor := bc.setTextColor(color)
+	page := bc.beginMarkedContent(strings.ToUpper(n.Data), "")
+	var err error
+	if bc.rtl() {
+		err = bc.renderRTLContent(n, prefix, style.text.Alignment)
+	} else {
+		bc.writeWithFallback(prefix)
+		err = bc.renderChildren(n)
+	}
+	bc.endMarkedContent(page)
+	bc.restoreTextColor(previousColor)
+	if err != nil {
 		return err
 	}
-	bc.pdf.Ln(defaultLineHeight * 2)
+	bc.pdf.Ln(style.spaceAfter)
 	return nil
 }
 
+// fitsWithNext reports whether a heading of the given height, plus the
+// configured number of following body lines, fits on the current page.
+//
+// Parameters:
+//   - headingHeight: Space used by the heading, including the space above it, in millimeters
+//   - spaceAfter: Space below the heading in millimeters
+//
+// Returns:
+//   - bool: true if the heading can stay on the current page
+func (bc *BookCompiler) fitsWithNext(headingHeight, spaceAfter float64) bool {
+	_, bottom := bc.pdf.GetAutoPageBreak()
+	needed := headingHeight + spaceAfter + bc.lineHeight()*float64(bc.keepWithNextLines)
+	return bc.pdf.GetY()+needed <= bc.getPageHeight()-bottom
+}
+
 // renderBlockElement processes block-level HTML elements.
 // Handles paragraphs, blockquotes, and code blocks with appropriate
 // styling and spacing.
@@ -71,40 +203,129 @@ func (bc *BookCompiler) renderHeading(n *html.Node) error {
 //
 // Manages page breaks and applies element-specific formatting.
 func (bc *BookCompiler) renderBlockElement(n *html.Node) error {
-	if bc.pdf.GetY() > bc.getPageHeight()-50 {
-		bc.pdf.AddPage()
-	}
-
 	switch n.Data {
 	case "blockquote":
-		bc.pdf.Ln(defaultLineHeight)
+		if kind, ok := blockquoteAlert(n); ok {
+			bc.breakBeforeBlock(defaultLineHeight)
+			return bc.renderCallout(n, kind)
+		}
+		before, after := bc.spacingFor("blockquote", defaultLineHeight, defaultLineHeight)
+		bc.breakBeforeBlock(before)
+		bc.pdf.Ln(before)
 		err := bc.renderBlockquote(n)
-		bc.pdf.Ln(defaultLineHeight)
+		bc.pdf.Ln(after)
 		return err
 	case "pre", "code":
-		bc.pdf.Ln(defaultLineHeight)
+		if lang, fn, ok := bc.fenceRenderer(n); ok {
+			return bc.renderFence(n, lang, fn)
+		}
+		before, after := bc.spacingFor("code", defaultLineHeight, defaultLineHeight)
+		bc.breakBeforeBlock(before)
+		bc.pdf.Ln(before)
 		err := bc.renderCode(n)
-		bc.pdf.Ln(defaultLineHeight)
+		bc.pdf.Ln(after)
 		return err
 	default: // p
-		bc.pdf.SetFont(bc.textFont, fontStyleNormal, defaultFontSize)
-		bc.pdf.Ln(defaultLineHeight / 2)
-		if err := bc.renderChildren(n); err != nil {
+		if isTableCaption(n) {
+			return nil
+		}
+		if isPageBreakMarker(n) {
+			bc.renderPageBreak()
+			return nil
+		}
+		style, color := bc.paragraphStyle(n)
+		before, after := bc.spacingFor("p", bc.paragraphSpacing, 0)
+		bc.setFont(style.FontFamily, style.Style, style.Size)
+		bc.breakBeforeBlock(before)
+		bc.pdf.Ln(before)
+		if color != nil {
+			defer bc.restoreTextColor(bc.setTextColor(*color))
+		}
+		if bc.rtl() {
+			if err := bc.renderRTLContent(n, "", style.Alignment); err != nil {
+				return err
+			}
+			bc.pdf.Ln(bc.lineHeight())
+			bc.pdf.Ln(after)
+			return nil
+		}
+		if bc.widowOrphanControl {
+			restore := bc.controlWidowsAndOrphans(n)
+			defer restore()
+		}
+		if bc.dropCapPending && n.Parent != nil && n.Parent.Data == "body" {
+			bc.dropCapPending = false
+			bc.startDropCap(n)
+		} else if style.Alignment == AlignCenter || style.Alignment == AlignRight {
+			bc.alignBlock(n, style.Alignment)
+		} else if bc.paragraphIndent > 0 && bc.indentsParagraph(n) {
+			bc.pdf.SetX(bc.pdf.GetX() + bc.paragraphIndent)
+		}
+		err := bc.renderChildren(n)
+		bc.endDropCap()
+		if err != nil {
 			return err
 		}
-		bc.pdf.Ln(defaultLineHeight)
+		bc.pdf.Ln(bc.lineHeight())
+		if after > 0 {
+			bc.pdf.Ln(after)
+		}
 	}
 	return nil
 }
 
-// setHeadingStyle applies consistent formatting for headings.
+// indentsParagraph reports whether a paragraph receives the first-line
+// indent. Only top-level paragraphs are indented, and never the first
+// paragraph of a file or the first one after a heading.
 //
 // Parameters:
-//   - size: Font size in points
-//   - spacing: Vertical spacing in millimeters
-func (bc *BookCompiler) setHeadingStyle(size, spacing float64) {
-	bc.pdf.SetFont(bc.chapterFont, fontStyleBold, size)
-	bc.pdf.Ln(spacing)
+//   - n: Paragraph element
+//
+// Returns:
+//   - bool: true if the first line should be indented
+func (bc *BookCompiler) indentsParagraph(n *html.Node) bool {
+	if n.Parent == nil || n.Parent.Data != "body" {
+		return false
+	}
+	prev := previousElement(n)
+	return prev != nil && !isHeading(prev)
+}
+
+// controlWidowsAndOrphans prevents a paragraph from leaving a single line
+// stranded at the bottom of a page (orphan) or the top of the next page
+// (widow). The wrapped line count is estimated with SplitText in the
+// paragraph font.
+//
+// Parameters:
+//   - n: Paragraph element about to be rendered
+//
+// Returns:
+//   - func(): Restores the page-break settings; call after the paragraph
+//
+// Short paragraphs and paragraphs that would be orphaned move to the next
+// page. Longer paragraphs that would leave a widow break earlier instead,
+// carrying enough lines to the next page.
+func (bc *BookCompiler) controlWidowsAndOrphans(n *html.Node) func() {
+	_, bottom := bc.pdf.GetAutoPageBreak()
+	restore := func() { bc.pdf.SetAutoPageBreak(true, bottom) }
+
+	width, height := bc.pdf.GetPageSize()
+	left, _, right, _ := bc.pdf.GetMargins()
+	lines := len(bc.SplitText(bc.cleanText(getTextContent(n)), width-left-right))
+	available := int((height - bottom - bc.pdf.GetY()) / bc.lineHeight())
+	if lines <= available {
+		return restore
+	}
+
+	carried := lines - available
+	switch {
+	case available < minOrphanLines || lines <= shortParagraphLines:
+		bc.pdf.AddPage()
+	case carried < minWidowLines:
+		extra := float64(minWidowLines-carried) * bc.lineHeight()
+		bc.pdf.SetAutoPageBreak(true, bottom+extra)
+	}
+	return restore
 }
 
 // restoreTextState restores previously saved text formatting.
@@ -112,34 +333,142 @@ func (bc *BookCompiler) setHeadingStyle(size, spacing float64) {
 // Parameters:
 //   - state: TextState containing saved formatting options
 func (bc *BookCompiler) restoreTextState(state TextState) {
-	bc.pdf.SetFont(state.FontFamily, state.Style, state.Size)
+	bc.setFont(state.FontFamily, state.Style, state.Size)
 }
 
-// renderHorizontalRule draws a horizontal line across the page width.
-// Adds vertical spacing after the line.
+// setTextColor applies a temporary text color and returns the color that
+// was active before, for use with restoreTextColor.
+//
+// Parameters:
+//   - color: Text color to apply
+//
+// Returns:
+//   - rgbColor: The previously active text color
+func (bc *BookCompiler) setTextColor(color rgbColor) rgbColor {
+	r, g, b := bc.pdf.GetTextColor()
+	bc.pdf.SetTextColor(color.r, color.g, color.b)
+	return rgbColor{r, g, b}
+}
+
+// restoreTextColor restores a text color saved by setTextColor.
+//
+// Parameters:
+//   - color: Previously active text color
+func (bc *BookCompiler) restoreTextColor(color rgbColor) {
+	bc.pdf.SetTextColor(color.r, color.g, color.b)
+}
+
+// setFont selects a font and records it as the current text state, so that
+// temporary font switches such as glyph fallback can restore it.
+//
+// Parameters:
+//   - family: Font family name
+//   - style: Font style ("", "B", "I", "BI")
+//   - size: Font size in points
+func (bc *BookCompiler) setFont(family, style string, size float64) {
+	bc.font = TextState{FontFamily: family, Style: style, Size: size}
+	bc.pdf.SetFont(family, style, size)
+}
+
+// combineStyles adds a style flag to an existing gofpdf style string,
+// returning the flags in the canonical "BIU" order gofpdf expects.
+//
+// Parameters:
+//   - style: Current style ("", "B", "I", "BI", ...)
+//   - add: Style flag to add
+//
+// Returns:
+//   - string: Combined style string
+func combineStyles(style, add string) string {
+	combined := style + add
+	var out strings.Builder
+	for _, flag := range []string{fontStyleBold, fontStyleItalic, "U"} {
+		if strings.Contains(combined, flag) {
+			out.WriteString(flag)
+		}
+	}
+	return out.String()
+}
+
+// renderHorizontalRule renders a thematic break in the configured style:
+// a horizontal line between the page margins, a centered asterism, or blank
+// space. Adds vertical spacing after the break.
 //
 // Returns:
 //   - error: Any drawing errors encountered
 func (bc *BookCompiler) renderHorizontalRule() error {
-	x := bc.pdf.GetX()
+	switch bc.thematicBreakStyle {
+	case ThematicBreakAsterisks:
+		bc.pdf.Ln(bc.lineHeight())
+		left, _, _, _ := bc.pdf.GetMargins()
+		bc.pdf.SetX(left)
+		bc.pdf.CellFormat(0, bc.lineHeight(), sceneBreakText, "", 1, AlignCenter, false, 0, "")
+		bc.pdf.Ln(bc.lineHeight())
+		return nil
+	case ThematicBreakSpace:
+		bc.pdf.Ln(bc.lineHeight() * 2)
+		return nil
+	}
+
+	// Span the content width regardless of list or blockquote indentation
+	width, _ := bc.pdf.GetPageSize()
+	left, _, right, _ := bc.pdf.GetMargins()
 	y := bc.pdf.GetY()
-	bc.pdf.Line(x, y, x+pageWidth, y)
+	bc.pdf.Line(left, y, width-right, y)
 	bc.pdf.Ln(8)
 	return nil
 }
 
+// isPageBreakMarker reports whether a node is a manual page break marker:
+// a <!-- pagebreak --> comment or a paragraph containing only \pagebreak.
+//
+// Parameters:
+//   - n: Node to check
+//
+// Returns:
+//   - bool: true if a page break should be inserted in place of the node
+func isPageBreakMarker(n *html.Node) bool {
+	switch {
+	case n.Type == html.CommentNode:
+		return strings.EqualFold(strings.TrimSpace(n.Data), pageBreakComment)
+	case n.Type == html.ElementNode && n.Data == "p":
+		return strings.TrimSpace(getTextContent(n)) == pageBreakToken
+	}
+	return false
+}
+
+// renderPageBreak starts a new page for a manual page break marker. The
+// page is added when the next content is rendered, and the break is
+// skipped on a page that is still empty, so no blank pages are produced,
+// including at the end of a chapter.
+func (bc *BookCompiler) renderPageBreak() {
+	_, top, _, _ := bc.pdf.GetMargins()
+	if bc.pdf.GetY() > top {
+		bc.pageBreakPending = true
+	}
+}
+
 // handleImage processes and renders a JPEG image with optional caption.
 // Handles image scaling, page breaks, and positioning.
 //
 // Parameters:
 //   - src: Image file path
-//   - alt: Optional caption text
+//   - caption: Optional caption text, rendered centered in italics
+//   - alt: Alternate description recorded in tagged PDFs
 //
 // Returns:
 //   - error: Image processing or rendering errors
 //
-// Supports only JPEG images and automatically scales them to fit the page width.
-func (bc *BookCompiler) handleImage(src, alt string) error {
+// Supports JPEG images, and SVG images converted by the SVG rasterizer,
+// and automatically scales them to fit the page width.
+func (bc *BookCompiler) handleImage(src, caption, alt string) error {
+	if isSVGImage(src) {
+		raster, err := bc.rasterizeSVG(src)
+		if err != nil {
+			return err
+		}
+		src = raster
+	}
 	if !isJPEGImage(src) {
 		return fmt.Errorf("unsupported image format: %s", src)
 	}
@@ -148,30 +477,54 @@ func (bc *BookCompiler) handleImage(src, alt string) error {
 	x := bc.pdf.GetX()
 	y := bc.pdf.GetY()
 
-	imgInfo := bc.pdf.RegisterImage(src, "")
+	imgInfo := bc.registerImage(src, imageWidth)
 	if imgInfo == nil {
 		return fmt.Errorf("failed to load image: %s", src)
 	}
 
-	imgHeight := (imgInfo.Height() * 100) / imgInfo.Width()
-	if y+imgHeight > bc.getPageHeight()-30 {
+	imgHeight := (imgInfo.Height() * imageWidth) / imgInfo.Width()
+	_, top, _, _ := bc.pdf.GetMargins()
+	if y > top && imgHeight > bc.spaceLeft() {
 		bc.pdf.AddPage()
 		y = bc.pdf.GetY()
 	}
 
-	bc.pdf.Image(src, x, y, 100, 0, false, "", 0, "")
+	page := bc.beginMarkedContent("Figure", alt)
+	bc.pdf.Image(src, x, y, imageWidth, 0, false, "", 0, "")
+	bc.endMarkedContent(page)
 	bc.pdf.SetY(y + imgHeight + 5)
 
-	if alt != "" {
-		bc.pdf.SetFont(bc.textFont, fontStyleItalic, 10)
-		bc.pdf.Write(defaultLineHeight, alt)
-		bc.pdf.Ln(defaultLineHeight)
-	}
+	bc.renderCaption(caption)
 
 	bc.pdf.Ln(defaultLineHeight)
 	return nil
 }
 
+// renderCaption writes a centered italic caption below a figure.
+//
+// Parameters:
+//   - caption: Caption text. Empty captions are skipped.
+func (bc *BookCompiler) renderCaption(caption string) {
+	caption = bc.normalizeText(caption)
+	if caption == "" {
+		return
+	}
+
+	bc.setFont(bc.textFont, fontStyleItalic, captionFontSize)
+	bc.pdf.SetX(bc.margin)
+	if isCoreText(caption) {
+		bc.pdf.MultiCell(0, bc.lineHeight(), caption, "", AlignCenter, false)
+	} else {
+		// Symbols are drawn in their own font, one wrapped line at a time
+		width := bc.pageWidth - 2*bc.margin
+		for _, line := range bc.SplitText(caption, width-2*bc.pdf.GetCellMargin()) {
+			bc.pdf.SetX(bc.margin)
+			bc.cellWithFallback(width, bc.lineHeight(), line, 1, AlignCenter, 0)
+		}
+	}
+	bc.setFont(bc.textFont, fontStyleNormal, defaultFontSize)
+}
+
 // renderListElement handles ordered and unordered lists.
 // Supports nested lists with proper indentation.
 //
@@ -195,23 +548,43 @@ func (bc *BookCompiler) renderListElement(n *html.Node) error {
 		}
 		bc.pdf.Ln(5)
 	case "li":
+		marker := "• "
+		if parent := findParent(n, "ol"); parent != nil {
+			number := countPreviousSiblings(n) + 1
+			marker = fmt.Sprintf("%d. ", number)
+		}
+		if bc.rtl() {
+			return bc.renderRTLListItem(n, marker)
+		}
+
 		indent := indentWidth
 		if parent := findParent(n, "li"); parent != nil {
 			indent += indentWidth
 		}
 
 		bc.pdf.SetX(bc.pdf.GetX() + indent)
-		if parent := findParent(n, "ol"); parent != nil {
-			number := countPreviousSiblings(n) + 1
-			bc.pdf.Write(defaultLineHeight, fmt.Sprintf("%d. ", number))
-		} else {
-			bc.pdf.Write(defaultLineHeight, "• ")
-		}
+		bc.writeListMarker(marker)
 		if err := bc.renderChildren(n); err != nil {
 			return err
 		}
-		bc.pdf.Ln(5)
+		bc.pdf.Ln(bc.lineHeight())
 		bc.pdf.SetX(bc.pdf.GetX() - indent)
 	}
 	return nil
 }
+
+// writeListMarker writes a bullet or list number in the configured marker
+// color, restoring the previous text color before item content is rendered.
+//
+// Parameters:
+//   - marker: Marker text including trailing space
+func (bc *BookCompiler) writeListMarker(marker string) {
+	if bc.listMarkerColor == nil {
+		bc.pdf.Write(bc.lineHeight(), marker)
+		return
+	}
+
+	previous := bc.setTextColor(*bc.listMarkerColor)
+	bc.pdf.Write(bc.lineHeight(), marker)
+	bc.restoreTextColor(previous)
+}
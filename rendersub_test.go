@@ -0,0 +1,130 @@
+package bookie
+
+import (
+	"strings"
+	"testing"
+)
+
+// renderRecorded renders markdown snippets in order into a new recorded
+// document, calling between, if not nil, after each snippet but the last.
+func renderRecorded(t *testing.T, bc *BookCompiler, between func(), snippets ...string) {
+	t.Helper()
+	for i, md := range snippets {
+		if err := bc.RenderMarkdown([]byte(md)); err != nil {
+			t.Fatalf("RenderMarkdown: %v", err)
+		}
+		if between != nil && i < len(snippets)-1 {
+			between()
+		}
+	}
+}
+
+// moveToBottom places the cursor the given distance above the bottom
+// margin of the current page.
+func moveToBottom(bc *BookCompiler, distance float64) {
+	_, bottom := bc.pdf.GetAutoPageBreak()
+	bc.pdf.SetY(bc.getPageHeight() - bottom - distance)
+}
+
+func TestHeadingKeepWithNextUsesSpaceAfter(t *testing.T) {
+	tests := []struct {
+		spaceAfter float64
+		wantPage   int
+	}{
+		{spaceAfter: 10, wantPage: 1},
+		{spaceAfter: 80, wantPage: 2},
+	}
+	for _, tt := range tests {
+		bc, recording := newRecordedCompiler(t, t.TempDir())
+		bc.SetHeadingStyle(2, TextStyle{Style: fontStyleBold, Size: 20}, 5, tt.spaceAfter)
+		renderRecorded(t, bc, func() { moveToBottom(bc, 60) }, "Opening text.", "## Kept Heading\n\nBody text.")
+
+		if page := recording().pageOf("Kept Heading"); page != tt.wantPage {
+			t.Errorf("spaceAfter %.0f: heading on page %d, want %d", tt.spaceAfter, page, tt.wantPage)
+		}
+	}
+}
+
+func TestListMarkerColor(t *testing.T) {
+	bc, recording := newRecordedCompiler(t, t.TempDir())
+	bc.SetListMarkerColor(200, 0, 0)
+	renderRecorded(t, bc, nil, "- Item text\n")
+
+	var marker, item *pdfCall
+	for _, call := range recording().textCalls() {
+		call := call
+		switch {
+		case strings.HasPrefix(call.text, "•"):
+			marker = &call
+		case strings.Contains(call.text, "Item text"):
+			item = &call
+		}
+	}
+	if marker == nil || item == nil {
+		t.Fatalf("marker drawn: %v, item text drawn: %v; want both", marker != nil, item != nil)
+	}
+	if marker.textColor != [3]int{200, 0, 0} {
+		t.Errorf("bullet drawn in %v, want [200 0 0]", marker.textColor)
+	}
+	if item.textColor != [3]int{0, 0, 0} {
+		t.Errorf("item text drawn in %v, want black", item.textColor)
+	}
+}
+
+func TestBlockBreakUsesSpaceBefore(t *testing.T) {
+	tests := []struct {
+		name      string
+		spacing   float64
+		threshold float64
+		distance  float64
+		wantPage  int
+		wantGap   bool // Whether the space before is kept on the new page
+	}{
+		{name: "fits without spacing", spacing: 0, distance: 7, wantPage: 1},
+		{name: "spacing does not fit", spacing: 12, distance: 14, wantPage: 2, wantGap: true},
+		{name: "configured threshold", spacing: 0, threshold: 30, distance: 20, wantPage: 2},
+	}
+	for _, tt := range tests {
+		bc, recording := newRecordedCompiler(t, t.TempDir())
+		bc.SetParagraphStyle(0, tt.spacing)
+		bc.SetPageBreakThreshold(tt.threshold)
+		renderRecorded(t, bc, func() { moveToBottom(bc, tt.distance) }, "Opening text.", "Closing paragraph.")
+
+		pdf := recording()
+		page := pdf.pageOf("Closing paragraph")
+		if page != tt.wantPage {
+			t.Errorf("%s: paragraph on page %d, want %d", tt.name, page, tt.wantPage)
+			continue
+		}
+		if !tt.wantGap {
+			continue
+		}
+		_, top, _, _ := pdf.GetMargins()
+		for _, call := range pdf.textCalls() {
+			if strings.Contains(call.text, "Closing paragraph") && call.y < top+tt.spacing {
+				t.Errorf("%s: paragraph at y %.1f, want at least %.1f", tt.name, call.y, top+tt.spacing)
+			}
+		}
+	}
+}
+
+func TestChapterOpeningHeadingStaysOnOpener(t *testing.T) {
+	root := writeBook(t, map[string]string{
+		"Episode01/content.md": "# Opening Title\n\nOpening body text.\n\n# Later Title\n\nLater body text.\n",
+	})
+	_, pdf := compileRecorded(t, root, func(bc *BookCompiler) {
+		bc.SetChapterSubtitles(false)
+	})
+
+	// Contents on page 1; the chapter title, its first heading, and the
+	// text below it on page 2; the next H1 on a page of its own.
+	for text, want := range map[string]int{
+		"Opening Title":     2,
+		"Opening body text": 2,
+		"Later Title":       3,
+	} {
+		if page := pdf.pageOf(text); page != want {
+			t.Errorf("%q on page %d, want %d", text, page, want)
+		}
+	}
+}
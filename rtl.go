@@ -0,0 +1,278 @@
+package bookie
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// mirroredRunes maps paired punctuation to its mirror image, which is
+// drawn in right-to-left text so that "(" still opens a parenthesis.
+var mirroredRunes = map[rune]rune{
+	'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{', '<': '>', '>': '<',
+	'«': '»', '»': '«',
+}
+
+// rtl reports whether text is laid out right to left.
+func (bc *BookCompiler) rtl() bool {
+	return bc.textDirection == TextDirectionRTL
+}
+
+// isRTLRune reports whether a rune belongs to a right-to-left script.
+func isRTLRune(r rune) bool {
+	return unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko)
+}
+
+// visualOrder reorders one line of right-to-left text from the order it
+// is written in to the left-to-right order it is drawn in. This is a
+// simplified form of the Unicode bidirectional algorithm: runs of
+// left-to-right letters and digits keep their order, punctuation between
+// two such runs belongs to them, and everything else is reversed, with
+// brackets mirrored. Arabic letters are not shaped.
+//
+// Parameters:
+//   - line: Text of one line, in logical order
+//
+// Returns:
+//   - string: The line in visual order
+func visualOrder(line string) string {
+	runes := []rune(line)
+	ltr := make([]bool, len(runes))
+	for i, r := range runes {
+		ltr[i] = (unicode.IsLetter(r) || unicode.IsDigit(r)) && !isRTLRune(r)
+	}
+
+	// Neutral runes between two left-to-right runes join them
+	for i := 0; i < len(runes); {
+		if ltr[i] || isRTLRune(runes[i]) {
+			i++
+			continue
+		}
+		end := i
+		for end < len(runes) && !ltr[end] && !isRTLRune(runes[end]) {
+			end++
+		}
+		if i > 0 && end < len(runes) && ltr[i-1] && ltr[end] {
+			for j := i; j < end; j++ {
+				ltr[j] = true
+			}
+		}
+		i = end
+	}
+
+	var out strings.Builder
+	for end := len(runes); end > 0; {
+		start := end - 1
+		for start > 0 && ltr[start-1] == ltr[end-1] {
+			start--
+		}
+		if ltr[end-1] {
+			out.WriteString(string(runes[start:end]))
+		} else {
+			for i := end - 1; i >= start; i-- {
+				if mirror, ok := mirroredRunes[runes[i]]; ok {
+					out.WriteRune(mirror)
+				} else {
+					out.WriteRune(runes[i])
+				}
+			}
+		}
+		end = start
+	}
+	return out.String()
+}
+
+// measureText returns the width of normalized text in the current font,
+// measuring characters drawn from the glyph fallback or symbol font in
+// that font.
+//
+// Parameters:
+//   - text: Text as passed to writeWithFallback
+//
+// Returns:
+//   - float64: Width in millimeters
+func (bc *BookCompiler) measureText(text string) float64 {
+	width := 0.0
+	for _, r := range text {
+		family, glyph, ok := bc.glyphFor(r)
+		switch {
+		case !ok:
+		case family == "":
+			width += bc.pdf.GetStringWidth(glyph)
+		default:
+			bc.pdf.SetFont(family, fontStyleNormal, bc.font.Size)
+			width += bc.pdf.GetStringWidth(glyph)
+			bc.pdf.SetFont(bc.font.FontFamily, bc.font.Style, bc.font.Size)
+		}
+	}
+	return width
+}
+
+// writeRTL writes text right to left in the current font: it is wrapped
+// between the margins and each line is right-aligned, or centered, in
+// visual order. The cursor is left at the end of the last line. Inline
+// formatting is not applied to right-to-left text.
+//
+// Parameters:
+//   - raw: Text in logical order
+//   - align: AlignCenter to center the lines; other values right-align
+func (bc *BookCompiler) writeRTL(raw, align string) {
+	text := strings.TrimSpace(bc.normalizeText(raw))
+	if text == "" {
+		return
+	}
+
+	width, _ := bc.pdf.GetPageSize()
+	left, _, right, _ := bc.pdf.GetMargins()
+	// Write insets text by the cell margin on both sides; the small
+	// allowance keeps rounding from wrapping a line that just fits
+	available := width - left - right - 2*bc.pdf.GetCellMargin() - 0.01
+
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && bc.measureText(line+" "+word) > available {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	lines = append(lines, line)
+
+	for i, line := range lines {
+		if i > 0 {
+			bc.pdf.Ln(bc.lineHeight())
+		}
+		visual := visualOrder(line)
+		offset := max(0, available-bc.measureText(visual))
+		if align == AlignCenter {
+			offset /= 2
+		}
+		bc.pdf.SetX(left + offset)
+		bc.writeWithFallback(visual)
+	}
+}
+
+// rtlTextElements are the inline formatting elements whose text is
+// written as part of the surrounding right-to-left run.
+var rtlTextElements = map[string]bool{
+	"em": true, "i": true, "cite": true, "strong": true, "b": true, "u": true,
+	"del": true, "s": true, "mark": true, "code": true, "a": true, "span": true,
+}
+
+// isRTLText reports whether a node is plain text for right-to-left
+// layout: a text node, or inline formatting without an id that holds only
+// such nodes. Anything else, such as an image, math, a footnote reference,
+// an anchor, or an index marker, needs rendering of its own.
+func isRTLText(n *html.Node) bool {
+	switch n.Type {
+	case html.TextNode:
+		return true
+	case html.ElementNode:
+		if !rtlTextElements[n.Data] || getAttr(n, "id") != "" || hasClass(n, mathClass) {
+			return false
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if !isRTLText(c) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// renderRTLContent renders the children of an element right to left.
+// Runs of plain text are gathered and written with writeRTL; other
+// children are rendered with renderNode between the runs, each starting
+// on a line of its own. The paragraphs of a list item are written as part
+// of its runs, so that they follow its marker.
+//
+// Parameters:
+//   - n: Element whose children are rendered
+//   - prefix: Text written at the start of the first run, such as a
+//     list marker
+//   - align: Alignment passed to writeRTL
+//
+// Returns:
+//   - error: Any rendering errors encountered in element children
+func (bc *BookCompiler) renderRTLContent(n *html.Node, prefix, align string) error {
+	var run strings.Builder
+	run.WriteString(prefix)
+	flush := func() {
+		if strings.TrimSpace(run.String()) != "" {
+			if bc.pageBreakPending {
+				bc.pageBreakPending = false
+				bc.pdf.AddPage()
+			}
+			bc.endRTLLine()
+			bc.writeRTL(run.String(), align)
+		}
+		run.Reset()
+	}
+
+	var walk func(parent *html.Node) error
+	walk = func(parent *html.Node) error {
+		for c := parent.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case isRTLText(c):
+				run.WriteString(getTextContent(c))
+			case n.Data == "li" && c.Type == html.ElementNode && c.Data == "p":
+				if err := walk(c); err != nil {
+					return err
+				}
+				run.WriteString(" ")
+			default:
+				flush()
+				if c.Type != html.CommentNode {
+					bc.endRTLLine()
+				}
+				if err := bc.renderNode(c); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(n); err != nil {
+		return err
+	}
+	flush()
+	return nil
+}
+
+// endRTLLine moves to the next line if anything has been written on the
+// current one, so that right-to-left runs and the elements between them
+// do not overlap.
+func (bc *BookCompiler) endRTLLine() {
+	left, _, _, _ := bc.pdf.GetMargins()
+	if bc.pdf.GetX() > left+0.01 {
+		bc.pdf.Ln(bc.lineHeight())
+	}
+}
+
+// renderRTLListItem renders a list item right to left, with its marker at
+// the right and its indentation taken from the right margin. Nested lists
+// follow the item's text, indented further.
+//
+// Parameters:
+//   - n: List item element
+//   - marker: Bullet or number written before the text
+//
+// Returns:
+//   - error: Any rendering errors encountered in the item's content
+func (bc *BookCompiler) renderRTLListItem(n *html.Node, marker string) error {
+	_, _, right, _ := bc.pdf.GetMargins()
+	bc.pdf.SetRightMargin(right + indentWidth)
+	defer bc.pdf.SetRightMargin(right)
+
+	if err := bc.renderRTLContent(n, marker, AlignRight); err != nil {
+		return err
+	}
+	bc.endRTLLine()
+	return nil
+}
@@ -0,0 +1,126 @@
+package bookie
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// Page estimation parameters used by Stats.
+const (
+	estimatedWordsPerPage = 400 // Body words on a typical A4 page
+	estimatedImageWords   = 150 // Page space of one image, in words
+)
+
+// BookStats summarizes the size of a manuscript.
+type BookStats struct {
+	// Chapters holds per-chapter statistics in book order
+	Chapters []ChapterStats
+
+	// TotalWords is the sum of all chapter word counts
+	TotalWords int
+
+	// Images is the total number of image references
+	Images int
+
+	// EstimatedPages approximates the compiled page count, including the
+	// table of contents page
+	EstimatedPages int
+}
+
+// ChapterStats summarizes the size of a single chapter.
+type ChapterStats struct {
+	// Path is the chapter directory
+	Path string
+
+	// Title is the chapter title as shown in the PDF
+	Title string
+
+	// Words counts the words in paragraphs, headings, and table cells;
+	// code blocks are excluded
+	Words int
+
+	// Images is the number of image references in the chapter
+	Images int
+
+	// EstimatedPages approximates the pages the chapter will occupy
+	EstimatedPages int
+}
+
+// Stats computes word, image, and page statistics for the book without
+// generating a PDF. Chapters are discovered exactly as for Compile.
+//
+// Returns:
+//   - BookStats: Per-chapter and total statistics
+//   - error: Chapter discovery or file reading errors
+func (bc *BookCompiler) Stats() (BookStats, error) {
+	chapters, err := bc.getChapters()
+	if err != nil {
+		return BookStats{}, fmt.Errorf("failed to get chapters: %w", err)
+	}
+
+	stats := BookStats{EstimatedPages: 1}
+	for _, chapter := range chapters {
+		chapterStats := ChapterStats{
+			Path:  chapter.Path,
+			Title: chapterTitle(chapter),
+		}
+		for _, file := range chapter.Files {
+			words, images, err := bc.countMarkdown(file)
+			if err != nil {
+				return BookStats{}, fmt.Errorf("failed to count %s: %w", filepath.Base(file), err)
+			}
+			chapterStats.Words += words
+			chapterStats.Images += images
+		}
+
+		space := chapterStats.Words + chapterStats.Images*estimatedImageWords
+		chapterStats.EstimatedPages = int(math.Max(1, math.Ceil(float64(space)/estimatedWordsPerPage)))
+
+		stats.Chapters = append(stats.Chapters, chapterStats)
+		stats.TotalWords += chapterStats.Words
+		stats.Images += chapterStats.Images
+		stats.EstimatedPages += chapterStats.EstimatedPages
+	}
+
+	return stats, nil
+}
+
+// countMarkdown counts the words and images in a markdown file.
+//
+// Parameters:
+//   - file: Markdown file path
+//
+// Returns:
+//   - int: Words in paragraphs, headings, and table cells
+//   - int: Image references
+//   - error: File reading errors
+func (bc *BookCompiler) countMarkdown(file string) (int, int, error) {
+	content, err := bc.readFile(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	_, content = splitFrontMatter(content)
+
+	parser := blackfriday.New(blackfriday.WithExtensions(bc.markdownExtensions))
+	ast := parser.Parse(content)
+
+	words, images := 0, 0
+	ast.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering {
+			return blackfriday.GoToNext
+		}
+		switch node.Type {
+		case blackfriday.Paragraph, blackfriday.Heading, blackfriday.TableCell:
+			words += len(strings.Fields(getString(node)))
+		case blackfriday.Image:
+			images++
+		}
+		return blackfriday.GoToNext
+	})
+
+	return words, images, nil
+}
@@ -0,0 +1,388 @@
+package bookie
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ElementStyle is one entry of a styles file loaded with LoadStyles.
+// Fields left out keep the built-in defaults.
+//
+// Example file:
+//
+//	{
+//	    "h1": {"font": "Helvetica", "size": 28, "color": "#1F3A5F", "align": "C"},
+//	    "p": {"font": "Times", "size": 11, "spaceBefore": 2},
+//	    "blockquote": {"style": "I", "color": "#555555"},
+//	    "code": {"font": "Courier", "size": 9},
+//	    "table": {"size": 9}
+//	}
+type ElementStyle struct {
+	// FontFamily is the font to use (e.g., "Arial", "Times")
+	FontFamily string `json:"font"`
+
+	// Style is "" for normal, "B", "I", or "BI"; leave it out to keep the
+	// default
+	Style *string `json:"style"`
+
+	// Size is the font size in points
+	Size float64 `json:"size"`
+
+	// Color is the text color as "#RRGGBB"
+	Color string `json:"color"`
+
+	// SpaceBefore and SpaceAfter are the vertical gaps around the element
+	// in millimeters
+	SpaceBefore *float64 `json:"spaceBefore"`
+	SpaceAfter  *float64 `json:"spaceAfter"`
+
+	// Alignment is "L", "C", or "R"; headings and paragraphs that fit on
+	// one line are centered or right-aligned
+	Alignment string `json:"align"`
+}
+
+// styledElements lists the element types a styles file can configure.
+var styledElements = map[string]bool{
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"p": true, "blockquote": true, "code": true, "table": true,
+}
+
+// elementStyle is a styles file entry ready for rendering.
+type elementStyle struct {
+	// text holds the font settings; empty fields keep the defaults
+	text TextStyle
+
+	// hasStyle reports whether text.Style was set, since "" is a valid style
+	hasStyle bool
+
+	// color is the text color; nil keeps the current color
+	color *rgbColor
+
+	// spaceBefore and spaceAfter are nil to keep the default spacing
+	spaceBefore, spaceAfter *float64
+}
+
+// LoadStyles reads a JSON styles file mapping element types to font,
+// size, color, spacing, and alignment settings, replacing any styles
+// loaded before. The element types are h1 to h6, p, blockquote, code
+// (code blocks, and the font of inline code), and table. Elements and
+// fields not in the file keep their defaults. See ElementStyle for the
+// format.
+//
+// Paragraphs in a blockquote use the blockquote settings. Tables use the
+// font, size, color, and spacing; header rows stay bold. Alignment
+// applies to headings and paragraphs only.
+//
+// Parameters:
+//   - path: Path to the styles file
+//
+// Returns:
+//   - error: If the file cannot be read or contains an invalid entry
+func (bc *BookCompiler) LoadStyles(path string) error {
+	styles, err := readStyles(os.ReadFile, path)
+	if err != nil {
+		return err
+	}
+	bc.elementStyles = styles
+	return nil
+}
+
+// LoadChapterStyles reads a styles file applied on top of the book's
+// styles while one chapter is rendered, for example a handwriting font for
+// a chapter of letters. A chapter can also name its own styles file with
+// a "styles" front matter field, relative to the chapter directory, which
+// is applied on top of this one.
+//
+// Parameters:
+//   - chapter: Chapter directory name (e.g., "Episode03")
+//   - path: Path to the styles file, in the format of LoadStyles
+//
+// Returns:
+//   - error: If the file cannot be read or contains an invalid entry
+func (bc *BookCompiler) LoadChapterStyles(chapter, path string) error {
+	styles, err := readStyles(os.ReadFile, path)
+	if err != nil {
+		return err
+	}
+	if bc.chapterStyles == nil {
+		bc.chapterStyles = make(map[string]map[string]elementStyle)
+	}
+	bc.chapterStyles[chapter] = styles
+	return nil
+}
+
+// readStyles reads and validates a styles file.
+//
+// Parameters:
+//   - readFile: Function reading the file, e.g. os.ReadFile
+//   - path: Path to the styles file
+//
+// Returns:
+//   - map[string]elementStyle: The styles keyed by element type
+//   - error: If the file cannot be read or contains an invalid entry
+func readStyles(readFile func(string) ([]byte, error), path string) (map[string]elementStyle, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read styles: %w", err)
+	}
+
+	var entries map[string]ElementStyle
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse styles %s: %w", path, err)
+	}
+
+	styles := make(map[string]elementStyle, len(entries))
+	for element, entry := range entries {
+		style, err := resolveElementStyle(element, entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid style in %s: %w", path, err)
+		}
+		styles[element] = style
+	}
+	return styles, nil
+}
+
+// pushChapterStyles puts a chapter's style overrides on the style stack:
+// those loaded for its directory, then those named in its front matter.
+//
+// Parameters:
+//   - chapter: Chapter about to be rendered
+//
+// Returns:
+//   - func(): Removes the chapter's overrides; call after the chapter
+//   - error: If the front matter styles file cannot be loaded
+func (bc *BookCompiler) pushChapterStyles(chapter Chapter) (func(), error) {
+	depth := len(bc.styleStack)
+	pop := func() { bc.styleStack = bc.styleStack[:depth] }
+
+	if styles, ok := bc.chapterStyles[filepath.Base(chapter.Path)]; ok {
+		bc.styleStack = append(bc.styleStack, styles)
+	}
+	if file := chapter.Meta[stylesField]; file != "" {
+		styles, err := readStyles(bc.readFile, filepath.Join(chapter.Path, file))
+		if err != nil {
+			pop()
+			return nil, err
+		}
+		bc.styleStack = append(bc.styleStack, styles)
+	}
+	return pop, nil
+}
+
+// activeStyles returns the entries for an element type in effect at the
+// current position, from the book's styles up through the style stack.
+//
+// Parameters:
+//   - element: Element type (e.g., "p", "h2")
+//
+// Returns:
+//   - []elementStyle: The entries, each overriding those before it
+func (bc *BookCompiler) activeStyles(element string) []elementStyle {
+	var styles []elementStyle
+	if style, ok := bc.elementStyles[element]; ok {
+		styles = append(styles, style)
+	}
+	for _, layer := range bc.styleStack {
+		if style, ok := layer[element]; ok {
+			styles = append(styles, style)
+		}
+	}
+	return styles
+}
+
+// resolveElementStyle validates a styles file entry and converts it for
+// rendering.
+//
+// Parameters:
+//   - element: Element type the entry applies to
+//   - entry: Settings as read from the file
+//
+// Returns:
+//   - elementStyle: The converted entry
+//   - error: If the element type or any setting is invalid
+func resolveElementStyle(element string, entry ElementStyle) (elementStyle, error) {
+	style := elementStyle{
+		text:        TextStyle{FontFamily: entry.FontFamily, Size: entry.Size, Alignment: entry.Alignment},
+		spaceBefore: entry.SpaceBefore,
+		spaceAfter:  entry.SpaceAfter,
+	}
+
+	if !styledElements[element] {
+		return style, fmt.Errorf("unknown element %q", element)
+	}
+	if entry.Size < 0 {
+		return style, fmt.Errorf("%s: negative size %g", element, entry.Size)
+	}
+	if entry.Style != nil {
+		if strings.Trim(*entry.Style, fontStyleBold+fontStyleItalic) != "" {
+			return style, fmt.Errorf("%s: invalid font style %q", element, *entry.Style)
+		}
+		style.text.Style = combineStyles(*entry.Style, "")
+		style.hasStyle = true
+	}
+	switch entry.Alignment {
+	case "", AlignLeft, AlignCenter, AlignRight:
+	default:
+		return style, fmt.Errorf("%s: invalid alignment %q", element, entry.Alignment)
+	}
+	if entry.Color != "" {
+		color, err := parseHexColor(entry.Color)
+		if err != nil {
+			return style, fmt.Errorf("%s: %w", element, err)
+		}
+		style.color = &color
+	}
+	return style, nil
+}
+
+// parseHexColor parses a color written as "#RRGGBB".
+//
+// Parameters:
+//   - s: Color string
+//
+// Returns:
+//   - rgbColor: The parsed color
+//   - error: If s is not a valid color
+func parseHexColor(s string) (rgbColor, error) {
+	hex := strings.TrimPrefix(s, "#")
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return rgbColor{}, fmt.Errorf("invalid color %q, expected #RRGGBB", s)
+	}
+	return rgbColor{int(value >> 16), int(value >> 8 & 0xFF), int(value & 0xFF)}, nil
+}
+
+// styleFor applies the loaded style of an element type to its default
+// font settings.
+//
+// Parameters:
+//   - element: Element type (e.g., "p", "h2")
+//   - defaults: Font settings used when no style overrides them
+//
+// Returns:
+//   - TextStyle: The font settings to render with
+func (bc *BookCompiler) styleFor(element string, defaults TextStyle) TextStyle {
+	for _, style := range bc.activeStyles(element) {
+		if style.text.FontFamily != "" {
+			defaults.FontFamily = style.text.FontFamily
+		}
+		if style.hasStyle {
+			defaults.Style = style.text.Style
+		}
+		if style.text.Size > 0 {
+			defaults.Size = style.text.Size
+		}
+		if style.text.Alignment != "" {
+			defaults.Alignment = style.text.Alignment
+		}
+	}
+	return defaults
+}
+
+// spacingFor applies the loaded spacing of an element type to its default
+// spacing.
+//
+// Parameters:
+//   - element: Element type (e.g., "p", "h2")
+//   - before, after: Default space above and below the element in millimeters
+//
+// Returns:
+//   - float64, float64: The space to leave above and below the element
+func (bc *BookCompiler) spacingFor(element string, before, after float64) (float64, float64) {
+	for _, style := range bc.activeStyles(element) {
+		if style.spaceBefore != nil {
+			before = *style.spaceBefore
+		}
+		if style.spaceAfter != nil {
+			after = *style.spaceAfter
+		}
+	}
+	return before, after
+}
+
+// colorFor returns the loaded text color of an element type.
+//
+// Parameters:
+//   - element: Element type (e.g., "p", "h2")
+//
+// Returns:
+//   - *rgbColor: The color, or nil to keep the current color
+func (bc *BookCompiler) colorFor(element string) *rgbColor {
+	var color *rgbColor
+	for _, style := range bc.activeStyles(element) {
+		if style.color != nil {
+			color = style.color
+		}
+	}
+	return color
+}
+
+// alignBlock moves the cursor so that a heading or paragraph is centered
+// or right-aligned. Only blocks that fit on the rest of the line are
+// moved, since gofpdf's flowing text is always left-aligned.
+//
+// Parameters:
+//   - n: Element about to be rendered in the current font
+//   - align: AlignCenter or AlignRight; other values leave the cursor alone
+func (bc *BookCompiler) alignBlock(n *html.Node, align string) {
+	if align != AlignCenter && align != AlignRight {
+		return
+	}
+	width, _ := bc.pdf.GetPageSize()
+	_, _, right, _ := bc.pdf.GetMargins()
+	available := width - right - bc.pdf.GetX()
+
+	// Write insets text by the cell margin on both sides
+	textWidth := bc.pdf.GetStringWidth(strings.TrimSpace(bc.cleanText(getTextContent(n)))) + 2*bc.pdf.GetCellMargin()
+	if textWidth > available {
+		return
+	}
+	offset := available - textWidth
+	if align == AlignCenter {
+		offset /= 2
+	}
+	bc.pdf.SetX(bc.pdf.GetX() + offset)
+}
+
+// paragraphStyle returns the font settings and text color of a paragraph.
+// Paragraphs in a blockquote take any settings loaded for blockquotes.
+//
+// Parameters:
+//   - n: Paragraph element
+//
+// Returns:
+//   - TextStyle: The font settings and alignment to render with
+//   - *rgbColor: The text color, or nil to keep the current color
+func (bc *BookCompiler) paragraphStyle(n *html.Node) (TextStyle, *rgbColor) {
+	style := bc.styleFor("p", TextStyle{FontFamily: bc.textFont, Size: defaultFontSize})
+	color := bc.colorFor("p")
+	if findParent(n, "blockquote") != nil {
+		style = bc.styleFor("blockquote", style)
+		if quoteColor := bc.colorFor("blockquote"); quoteColor != nil {
+			color = quoteColor
+		}
+	}
+	return style, color
+}
+
+// styleForHeading returns the font and spacing of a heading level with
+// any loaded style applied.
+//
+// Parameters:
+//   - level: Heading level, 1 for h1 through 6 for h6
+//
+// Returns:
+//   - headingStyle: The heading's font, alignment, and spacing
+func (bc *BookCompiler) styleForHeading(level int) headingStyle {
+	element := fmt.Sprintf("h%d", level)
+	style := bc.headingStyles[level]
+	style.text = bc.styleFor(element, style.text)
+	style.spaceBefore, style.spaceAfter = bc.spacingFor(element, style.spaceBefore, style.spaceAfter)
+	return style
+}
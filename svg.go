@@ -0,0 +1,43 @@
+package bookie
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// svgExtension is the file extension of SVG images.
+const svgExtension = ".svg"
+
+// ErrNoSVGRasterizer indicates an SVG image was referenced without a
+// rasterizer set with SetSVGRasterizer.
+var ErrNoSVGRasterizer = errors.New("SVG requires a rasterizer")
+
+// isSVGImage reports whether an image path names an SVG file.
+func isSVGImage(src string) bool {
+	return strings.HasSuffix(strings.ToLower(src), svgExtension)
+}
+
+// rasterizeSVG converts an SVG image to a JPEG image with the configured
+// rasterizer. Each distinct SVG document is converted once per compiler.
+//
+// Parameters:
+//   - path: Resolved path of the SVG file
+//
+// Returns:
+//   - string: Path of the JPEG image
+//   - error: ErrNoSVGRasterizer, file reading, or rasterizer errors
+func (bc *BookCompiler) rasterizeSVG(path string) (string, error) {
+	if bc.svgRasterizer == nil {
+		return "", fmt.Errorf("%w: %s", ErrNoSVGRasterizer, path)
+	}
+	data, err := bc.readFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read SVG image: %w", err)
+	}
+	raster, err := bc.renderedImage("svg", string(data), bc.svgRasterizer)
+	if err != nil {
+		return "", fmt.Errorf("failed to rasterize %s: %w", path, err)
+	}
+	return raster, nil
+}
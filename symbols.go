@@ -0,0 +1,154 @@
+package bookie
+
+import "strings"
+
+// symbolFont is the core font common symbols are drawn from when no glyph
+// fallback font is set. gofpdf treats "symbol" as an alias for it.
+const symbolFont = "ZapfDingbats"
+
+// symbolGlyphs maps symbols the core text fonts lack to their code in
+// symbolFont. Emoji with a close dingbat equivalent (e.g., ✅ and ⭐) are
+// mapped to it.
+var symbolGlyphs = map[rune]byte{
+	'✂': 0x22, // scissors
+	'☎': 0x25, // telephone
+	'✈': 0x28, // airplane
+	'✉': 0x29, // envelope
+	'☛': 0x2A, // pointing hand
+	'☞': 0x2B, // pointing hand
+	'✌': 0x2C, // victory hand
+	'✍': 0x2D, // writing hand
+	'✎': 0x2E, // pencil
+	'✏': 0x2F, // pencil
+	'✓': 0x33, // check mark
+	'✔': 0x34, // heavy check mark
+	'✅': 0x34, // check mark emoji
+	'✕': 0x35, // multiplication x
+	'✖': 0x36, // heavy multiplication x
+	'✗': 0x37, // ballot x
+	'✘': 0x38, // heavy ballot x
+	'❌': 0x38, // cross mark emoji
+	'★': 0x48, // black star
+	'⭐': 0x48, // star emoji
+	'✩': 0x49, // outlined star
+	'●': 0x6C, // black circle
+	'■': 0x6E, // black square
+	'▲': 0x73, // black up triangle
+	'▼': 0x74, // black down triangle
+	'◆': 0x75, // black diamond
+	'❤': 0xA4, // heavy black heart
+	'♣': 0xA8, // club suit
+	'♦': 0xA9, // diamond suit
+	'♥': 0xAA, // heart suit
+	'♠': 0xAB, // spade suit
+	'➔': 0xD4, // heavy right arrow
+	'→': 0xD5, // right arrow
+	'↔': 0xD6, // left right arrow
+	'↕': 0xD7, // up down arrow
+}
+
+// isGlyphModifier reports whether a rune only modifies the presentation
+// of its neighbors, such as the variation selector in "✔️" or the joiner
+// in composite emoji. Such runes are dropped without a warning.
+func isGlyphModifier(r rune) bool {
+	return r == '\u200D' || r == '\uFE0E' || r == '\uFE0F'
+}
+
+// glyphFor selects the font that renders a rune.
+//
+// Parameters:
+//   - r: Rune to render
+//
+// Returns:
+//   - string: Font family to switch to, or empty for the current font
+//   - string: Text to write in that font
+//   - bool: False if no available font can render the rune
+//
+// Runes outside the core fonts use the glyph fallback font when one is
+// set, and otherwise symbolFont if it has an equivalent.
+func (bc *BookCompiler) glyphFor(r rune) (string, string, bool) {
+	switch {
+	case hasCoreGlyph(r):
+		return "", string(r), true
+	case bc.glyphFallbackFont != "":
+		return bc.glyphFallbackFont, string(r), true
+	}
+	if code, ok := symbolGlyphs[r]; ok {
+		return symbolFont, string([]byte{code}), true
+	}
+	return "", "", false
+}
+
+// glyphRun is a run of text drawn in one font.
+type glyphRun struct {
+	// family is the font to switch to, or empty for the current font
+	family string
+
+	// text is the text to write in that font
+	text string
+}
+
+// glyphRuns splits text into runs by the font that renders each rune, as
+// selected by glyphFor. Runes no font can render are dropped with a
+// warning.
+//
+// Parameters:
+//   - text: Normalized text
+//
+// Returns:
+//   - []glyphRun: Runs in text order
+func (bc *BookCompiler) glyphRuns(text string) []glyphRun {
+	var runs []glyphRun
+	var run strings.Builder
+	family := ""
+	flush := func() {
+		if run.Len() > 0 {
+			runs = append(runs, glyphRun{family: family, text: run.String()})
+			run.Reset()
+		}
+	}
+
+	for _, r := range text {
+		runFamily, glyph, ok := bc.glyphFor(r)
+		if r < 32 || !ok {
+			bc.warnUnsupportedGlyph(r)
+			continue
+		}
+		if runFamily != family {
+			flush()
+			family = runFamily
+		}
+		run.WriteString(glyph)
+	}
+	flush()
+	return runs
+}
+
+// setRunFont switches to the font of a glyph run: the run's family in its
+// regular style, or the current font for runs without one.
+//
+// Parameters:
+//   - family: Font family of the run
+func (bc *BookCompiler) setRunFont(family string) {
+	if family == "" {
+		bc.pdf.SetFont(bc.font.FontFamily, bc.font.Style, bc.font.Size)
+		return
+	}
+	bc.pdf.SetFont(family, fontStyleNormal, bc.font.Size)
+}
+
+// warnUnsupportedGlyph logs a warning the first time in a compile that a
+// rune is dropped because no font can render it.
+//
+// Parameters:
+//   - r: The dropped rune
+func (bc *BookCompiler) warnUnsupportedGlyph(r rune) {
+	if r < 32 || isGlyphModifier(r) || bc.unsupportedGlyphs[r] {
+		return
+	}
+	if bc.unsupportedGlyphs == nil {
+		bc.unsupportedGlyphs = make(map[rune]bool)
+	}
+	bc.unsupportedGlyphs[r] = true
+	bc.logWarning("Unsupported character %q (U+%04X) in %s was dropped; set a glyph fallback font to render it", r, r, bc.currentFile)
+}
@@ -4,22 +4,29 @@ package bookie
 
 import (
 	"errors"
+	"math"
 	"strings"
 
+	"github.com/jung-kurt/gofpdf"
 	"golang.org/x/net/html"
 )
 
 // Table layout constants define the default dimensions and styling for PDF tables.
 // All measurements are in millimeters unless otherwise specified.
 const (
-	tableWidth      = 170.0 // Total table width (A4 width minus margins)
-	tableLineHeight = 6.0   // Height of a single line in table cells
-	tableFontSize   = 10.0  // Font size for table content in points
+	tableWidth       = 170.0 // Total table width (A4 width minus margins)
+	tableLineHeight  = 6.0   // Height of a single line in table cells
+	tableFontSize    = 10.0  // Font size for table content in points
+	minColumnWidth   = 25.0  // Narrowest column before a table counts as wide
+	minTableFontSize = 6.0   // Smallest font size used when scaling tables
 
 	// Header cell background color (RGB values)
 	headerFillR = 240 // Red component
 	headerFillG = 240 // Green component
 	headerFillB = 240 // Blue component
+
+	// Default background color of striped rows (RGB gray level)
+	zebraFillGray = 248
 )
 
 // Table-related errors define common failure conditions during table processing.
@@ -59,30 +66,232 @@ type TableRow struct {
 //   - error: ErrEmptyTable if the table has no content to render
 //   - error: Any errors encountered during PDF generation
 //
+// Tables whose columns would be narrower than minColumnWidth are handled
+// according to the configured WideTableMode. A table to be rotated that
+// starts a page is scaled to fit instead, so that the page is not left
+// blank above its landscape page.
+//
 // The table is rendered at the current PDF cursor position with
-// the configured styling and dimensions.
+// the configured styling and dimensions. A caption element, or a
+// "Table N" label when numbering is enabled, is rendered above it. In
+// markdown, a paragraph starting with "Table:" directly before or after
+// the table serves as its caption.
 func (bc *BookCompiler) renderTable(n *html.Node) error {
 	if n == nil || n.Type != html.ElementNode || n.Data != "table" {
 		return ErrInvalidTable
 	}
 
-	headers, rows, err := bc.parseTableStructure(n)
+	headers, rows, footers, err := bc.parseTableStructure(n)
 	if err != nil {
 		return err
 	}
 
-	colCount := bc.determineColumnCount(headers, rows)
+	colCount := bc.determineColumnCount(headers, append(rows, footers...))
 	if colCount == 0 {
 		return ErrEmptyTable
 	}
+	if len(headers) > 0 {
+		headers = fitRow(headers, colCount)
+	}
+	for i := range rows {
+		rows[i] = fitRow(rows[i], colCount)
+	}
+	for i := range footers {
+		footers[i] = fitRow(footers[i], colCount)
+	}
+
+	// renderNode leaves a line above tables, so a table that starts a page
+	// begins one line below the top margin.
+	_, top, _, _ := bc.pdf.GetMargins()
+	startsPage := bc.pdf.GetY() <= top+defaultLineHeight
+
+	before, after := bc.spacingFor("table", 0, 0)
+	if before > 0 {
+		bc.pdf.Ln(before)
+	}
+	if after > 0 {
+		defer bc.pdf.Ln(after)
+	}
+
+	caption := strings.TrimSpace(getTextContent(findDescendant(n, "caption")))
+	if caption == "" {
+		caption = markdownTableCaption(n)
+	}
+	bc.renderCaption(bc.numberCaption(tableLabelPrefix, getAttr(n, "id"), caption))
+	if color := bc.colorFor("table"); color != nil {
+		defer bc.restoreTextColor(bc.setTextColor(*color))
+	}
+
+	if tableWidth/float64(colCount) >= minColumnWidth {
+		return bc.renderTableContent(headers, rows, footers, tableWidth/float64(colCount), bc.tableSize())
+	}
+
+	switch bc.wideTableMode {
+	case WideTableSplit:
+		return bc.renderSplitTable(headers, rows, footers, colCount)
+	case WideTableRotate:
+		if startsPage {
+			// A landscape page would leave the current page blank
+			return bc.renderScaledTable(headers, rows, footers, tableWidth)
+		}
+		return bc.renderRotatedTable(headers, rows, footers)
+	default:
+		return bc.renderScaledTable(headers, rows, footers, tableWidth)
+	}
+}
+
+// tableCaptionPrefix marks a markdown paragraph as a table caption.
+const tableCaptionPrefix = "Table:"
+
+// markdownTableCaption returns the caption given to a table by an
+// adjacent "Table: ..." paragraph (see captionedTable).
+//
+// Parameters:
+//   - table: Table element
+//
+// Returns:
+//   - string: Caption text without the prefix, or empty if there is none
+func markdownTableCaption(table *html.Node) string {
+	for _, p := range []*html.Node{previousElement(table), nextElement(table)} {
+		if captionedTable(p) == table {
+			text := strings.TrimSpace(getTextContent(p))
+			return strings.TrimSpace(strings.TrimPrefix(text, tableCaptionPrefix))
+		}
+	}
+	return ""
+}
+
+// isTableCaption reports whether a paragraph is a markdown table caption
+// (see markdownTableCaption), which is rendered with the table instead of
+// as body text.
+//
+// Parameters:
+//   - p: Paragraph element
+//
+// Returns:
+//   - bool: true if the paragraph captions an adjacent table
+func isTableCaption(p *html.Node) bool {
+	return captionedTable(p) != nil
+}
+
+// captionedTable returns the table a "Table: ..." paragraph captions: the
+// table directly after it, or else the table directly before it unless
+// that table is already captioned from above. A caption between two
+// tables therefore belongs to the second.
+//
+// Parameters:
+//   - p: Element that may be a caption paragraph
+//
+// Returns:
+//   - *html.Node: The captioned table, or nil if p is not a caption
+func captionedTable(p *html.Node) *html.Node {
+	if !hasCaptionPrefix(p) {
+		return nil
+	}
+	if next := nextElement(p); next != nil && next.Data == "table" {
+		return next
+	}
+	prev := previousElement(p)
+	if prev != nil && prev.Data == "table" && !hasCaptionPrefix(previousElement(prev)) {
+		return prev
+	}
+	return nil
+}
 
-	colWidth := tableWidth / float64(colCount)
-	return bc.renderTableContent(headers, rows, colWidth)
+// hasCaptionPrefix reports whether n is a paragraph whose text starts
+// with tableCaptionPrefix.
+func hasCaptionPrefix(n *html.Node) bool {
+	if n == nil || n.Data != "p" {
+		return false
+	}
+	return strings.HasPrefix(strings.TrimSpace(getTextContent(n)), tableCaptionPrefix)
+}
+
+// renderScaledTable renders a table within the given width, shrinking the
+// font proportionally when columns are narrower than minColumnWidth. The
+// font never shrinks below minTableFontSize, or the configured table font
+// size if that is smaller.
+func (bc *BookCompiler) renderScaledTable(headers []*html.Node, rows, footers [][]*html.Node, width float64) error {
+	colWidth := width / float64(bc.determineColumnCount(headers, append(rows, footers...)))
+
+	baseSize := bc.tableSize()
+	fontSize := baseSize
+	if colWidth < minColumnWidth {
+		fontSize = baseSize * colWidth / minColumnWidth
+		fontSize = math.Max(fontSize, math.Min(minTableFontSize, baseSize))
+	}
+
+	return bc.renderTableContent(headers, rows, footers, colWidth, fontSize)
+}
+
+// renderSplitTable renders a wide table as a series of column slices, each
+// on its own page. The first column is repeated in every slice as a key.
+func (bc *BookCompiler) renderSplitTable(headers []*html.Node, rows, footers [][]*html.Node, colCount int) error {
+	perSlice := int(math.Floor(tableWidth/minColumnWidth)) - 1
+	if perSlice < 1 {
+		perSlice = 1
+	}
+
+	for start := 1; start < colCount; start += perSlice {
+		end := start + perSlice
+		if end > colCount {
+			end = colCount
+		}
+
+		if start > 1 {
+			bc.pdf.AddPage()
+		}
+
+		sliceHeaders := sliceColumns(headers, start, end)
+		var sliceRows, sliceFooters [][]*html.Node
+		for _, row := range rows {
+			sliceRows = append(sliceRows, sliceColumns(row, start, end))
+		}
+		for _, row := range footers {
+			sliceFooters = append(sliceFooters, sliceColumns(row, start, end))
+		}
+
+		if err := bc.renderScaledTable(sliceHeaders, sliceRows, sliceFooters, tableWidth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderRotatedTable renders a wide table on a dedicated landscape page and
+// resumes portrait layout on the following page.
+func (bc *BookCompiler) renderRotatedTable(headers []*html.Node, rows, footers [][]*html.Node) error {
+	bc.pdf.AddPageFormat("L", gofpdf.SizeType{Wd: bc.pageWidth, Ht: bc.pageHeight})
+
+	width := bc.pageHeight - 2*bc.margin
+	if err := bc.renderScaledTable(headers, rows, footers, width); err != nil {
+		return err
+	}
+
+	bc.pdf.AddPageFormat("P", gofpdf.SizeType{Wd: bc.pageWidth, Ht: bc.pageHeight})
+	return nil
+}
+
+// sliceColumns returns the key column followed by columns [start, end) of a
+// row. Columns beyond the end of the row are left out.
+func sliceColumns(row []*html.Node, start, end int) []*html.Node {
+	if len(row) == 0 {
+		return nil
+	}
+
+	slice := []*html.Node{row[0]}
+	for i := start; i < end && i < len(row); i++ {
+		slice = append(slice, row[i])
+	}
+	return slice
 }
 
 // SplitText splits text into lines that fit within a specified width.
 // This is a public utility function used for text wrapping in table cells
-// and other contexts where text needs to fit within constraints.
+// and other contexts where text needs to fit within constraints. Text is
+// measured in the current font; use SplitTextInFont to measure it in the
+// font it will be drawn in.
 //
 // Parameters:
 //   - text: The input text to split. May contain multiple words/spaces.
@@ -93,7 +302,11 @@ func (bc *BookCompiler) renderTable(n *html.Node) error {
 //     Returns nil for empty input text.
 //
 // The function attempts to split on word boundaries when possible,
-// only splitting words when they exceed the width constraint.
+// only splitting words when they exceed the width constraint. With
+// hyphenation on, a word that does not fit is broken at a hyphenation
+// point instead of moving whole to the next line. A word wider than the
+// whole line, such as a long URL, is broken between characters without a
+// hyphen, as gofpdf's Write breaks it when drawing.
 func (bc *BookCompiler) SplitText(text string, width float64) []string {
 	if text == "" {
 		return nil
@@ -104,22 +317,26 @@ func (bc *BookCompiler) SplitText(text string, width float64) []string {
 	currentLine := ""
 
 	for _, word := range words {
-		testLine := currentLine
-		if testLine != "" {
-			testLine += " "
+		prefix := currentLine
+		if prefix != "" {
+			prefix += " "
 		}
-		testLine += word
 
-		if bc.pdf.GetStringWidth(testLine) > width {
-			if currentLine != "" {
+		for bc.pdf.GetStringWidth(prefix+word) > width {
+			if head, tail, ok := bc.hyphenateToFit(word, width-bc.pdf.GetStringWidth(prefix)); ok {
+				lines = append(lines, prefix+head)
+				word = tail
+			} else if currentLine != "" {
 				lines = append(lines, currentLine)
-				currentLine = word
 			} else {
-				lines = append(lines, word)
+				head := bc.fitRunes(word, width)
+				lines = append(lines, head)
+				word = word[len(head):]
 			}
-		} else {
-			currentLine = testLine
+			currentLine, prefix = "", ""
 		}
+
+		currentLine = prefix + word
 	}
 
 	if currentLine != "" {
@@ -129,34 +346,92 @@ func (bc *BookCompiler) SplitText(text string, width float64) []string {
 	return lines
 }
 
-// Internal helper functions below - documented for maintainability
+// SplitTextInFont splits text like SplitText, measuring it in the given
+// font rather than the current one, so that the lines match how the text
+// is drawn in that font. The current font is left unchanged.
+//
+// Parameters:
+//   - text: The input text to split
+//   - width: Maximum width in millimeters for each line
+//   - style: Font family, style, and size the text is drawn in
+//
+// Returns:
+//   - []string: Lines that fit within the width, or nil for empty text
+func (bc *BookCompiler) SplitTextInFont(text string, width float64, style TextStyle) []string {
+	bc.pdf.SetFont(style.FontFamily, style.Style, style.Size)
+	defer bc.pdf.SetFont(bc.font.FontFamily, bc.font.Style, bc.font.Size)
+	return bc.SplitText(text, width)
+}
 
-// parseTableStructure extracts headers and data rows from an HTML table node.
-// Returns the headers as strings and rows as string arrays.
-func (bc *BookCompiler) parseTableStructure(n *html.Node) ([]string, [][]string, error) {
-	var headers []string
-	var rows [][]string
+// Internal helper functions below - documented for maintainability
 
-	for tr := n.FirstChild; tr != nil; tr = tr.NextSibling {
-		if tr.Type != html.ElementNode || tr.Data != "tr" {
-			continue
+// fitRunes returns the longest prefix of a word that fits in the given
+// width in the current font, and at least its first rune.
+//
+// Parameters:
+//   - word: Word too wide for the width
+//   - width: Maximum width in millimeters
+//
+// Returns:
+//   - string: Prefix of word ending on a rune boundary
+func (bc *BookCompiler) fitRunes(word string, width float64) string {
+	end := 0
+	for i := range word {
+		if end > 0 && bc.pdf.GetStringWidth(word[:i]) > width {
+			return word[:end]
 		}
+		end = i
+	}
+	if end > 0 && bc.pdf.GetStringWidth(word) > width {
+		return word[:end]
+	}
+	return word
+}
+
+// parseTableStructure extracts headers, data rows, and footer rows from an
+// HTML table node. Rows are found directly in the table or inside thead,
+// tbody, and tfoot sections. Rows in thead, and rows of th cells elsewhere,
+// form the header; rows in tfoot are returned separately.
+func (bc *BookCompiler) parseTableStructure(n *html.Node) ([]*html.Node, [][]*html.Node, [][]*html.Node, error) {
+	var headers []*html.Node
+	var rows, footers [][]*html.Node
+
+	var collect func(parent *html.Node, section string)
+	collect = func(parent *html.Node, section string) {
+		for tr := parent.FirstChild; tr != nil; tr = tr.NextSibling {
+			if tr.Type != html.ElementNode {
+				continue
+			}
+			if tr.Data == "thead" || tr.Data == "tbody" || tr.Data == "tfoot" {
+				collect(tr, tr.Data)
+				continue
+			}
+			if tr.Data != "tr" {
+				continue
+			}
 
-		row, isHeader := bc.parseTableRow(tr)
-		if isHeader {
-			headers = append(headers, row...)
-		} else if len(row) > 0 {
-			rows = append(rows, row)
+			row, isHeader := bc.parseTableRow(tr)
+			switch {
+			case section == "tfoot":
+				if len(row) > 0 {
+					footers = append(footers, row)
+				}
+			case section == "thead" || isHeader:
+				headers = append(headers, row...)
+			case len(row) > 0:
+				rows = append(rows, row)
+			}
 		}
 	}
+	collect(n, "")
 
-	return headers, rows, nil
+	return headers, rows, footers, nil
 }
 
-// parseTableRow extracts cell content from a table row node.
-// Returns the cell contents and whether this is a header row.
-func (bc *BookCompiler) parseTableRow(tr *html.Node) ([]string, bool) {
-	var cells []string
+// parseTableRow collects the cell elements of a table row node.
+// Returns the cells and whether this is a header row.
+func (bc *BookCompiler) parseTableRow(tr *html.Node) ([]*html.Node, bool) {
+	var cells []*html.Node
 	isHeader := false
 
 	for td := tr.FirstChild; td != nil; td = td.NextSibling {
@@ -164,8 +439,7 @@ func (bc *BookCompiler) parseTableRow(tr *html.Node) ([]string, bool) {
 			continue
 		}
 
-		cellText := getTextContent(td)
-		cells = append(cells, cellText)
+		cells = append(cells, td)
 		isHeader = isHeader || td.Data == "th"
 	}
 
@@ -173,53 +447,69 @@ func (bc *BookCompiler) parseTableRow(tr *html.Node) ([]string, bool) {
 }
 
 // determineColumnCount calculates the number of columns needed for the table.
-// Uses header count if available, otherwise uses the first data row.
-func (bc *BookCompiler) determineColumnCount(headers []string, rows [][]string) int {
-	if len(headers) > 0 {
-		return len(headers)
+// Uses the widest of the header and all data rows.
+func (bc *BookCompiler) determineColumnCount(headers []*html.Node, rows [][]*html.Node) int {
+	count := len(headers)
+	for _, row := range rows {
+		if len(row) > count {
+			count = len(row)
+		}
 	}
-	if len(rows) > 0 {
-		return len(rows[0])
+	return count
+}
+
+// fitRow pads a row with empty cells, or truncates it, to exactly
+// colCount cells so the table grid stays rectangular.
+func fitRow(row []*html.Node, colCount int) []*html.Node {
+	if len(row) >= colCount {
+		return row[:colCount]
 	}
-	return 0
+	return append(row, make([]*html.Node, colCount-len(row))...)
 }
 
 // renderTableContent handles the PDF generation for the table content.
-// Applies appropriate styling and renders headers and data rows.
-func (bc *BookCompiler) renderTableContent(headers []string, rows [][]string, colWidth float64) error {
-	bc.pdf.SetFont(bc.textFont, "B", tableFontSize)
-
+// Applies appropriate styling and renders headers, data rows, and footer rows.
+func (bc *BookCompiler) renderTableContent(headers []*html.Node, rows, footers [][]*html.Node, colWidth, fontSize float64) error {
 	if len(headers) > 0 {
-		if err := bc.renderTableHeaders(headers, colWidth); err != nil {
+		if err := bc.renderTableHeaders(headers, colWidth, fontSize); err != nil {
 			return err
 		}
 	}
 
-	return bc.renderTableRows(rows, colWidth)
+	if err := bc.renderTableRows(rows, colWidth, fontSize); err != nil {
+		return err
+	}
+	return bc.renderTableFooters(footers, colWidth, fontSize)
 }
 
-// renderTableHeaders renders the table header row with background color.
-func (bc *BookCompiler) renderTableHeaders(headers []string, colWidth float64) error {
-	bc.pdf.SetFillColor(headerFillR, headerFillG, headerFillB)
+// renderTableHeaders renders the table header row in bold with background color.
+func (bc *BookCompiler) renderTableHeaders(headers []*html.Node, colWidth, fontSize float64) error {
+	fill := rgbColor{headerFillR, headerFillG, headerFillB}
+	return bc.renderTableRow(headers, colWidth, fontSize, "B", &fill)
+}
 
-	for _, header := range headers {
-		x := bc.pdf.GetX()
-		y := bc.pdf.GetY()
-		bc.pdf.Rect(x, y, colWidth, tableLineHeight, "F")
-		bc.pdf.Cell(colWidth, tableLineHeight, header)
+// renderTableRows renders all data rows. With zebra striping enabled,
+// every second row is filled with the stripe color.
+func (bc *BookCompiler) renderTableRows(rows [][]*html.Node, colWidth, fontSize float64) error {
+	for i, row := range rows {
+		var fill *rgbColor
+		if bc.tableZebra && i%2 == 1 {
+			fill = &bc.tableZebraColor
+		}
+		if err := bc.renderTableRow(row, colWidth, fontSize, "", fill); err != nil {
+			return err
+		}
 	}
-	bc.pdf.Ln(tableLineHeight)
 
 	return nil
 }
 
-// renderTableRows renders all data rows with appropriate heights.
-func (bc *BookCompiler) renderTableRows(rows [][]string, colWidth float64) error {
-	bc.pdf.SetFont(bc.textFont, "", tableFontSize)
-
-	for _, row := range rows {
-		maxHeight := bc.calculateRowHeight(row, colWidth)
-		if err := bc.renderTableRow(row, colWidth, maxHeight); err != nil {
+// renderTableFooters renders footer rows in bold over the header
+// background color, below the data rows.
+func (bc *BookCompiler) renderTableFooters(footers [][]*html.Node, colWidth, fontSize float64) error {
+	fill := rgbColor{headerFillR, headerFillG, headerFillB}
+	for _, row := range footers {
+		if err := bc.renderTableRow(row, colWidth, fontSize, "B", &fill); err != nil {
 			return err
 		}
 	}
@@ -227,13 +517,19 @@ func (bc *BookCompiler) renderTableRows(rows [][]string, colWidth float64) error
 	return nil
 }
 
-// calculateRowHeight determines the maximum height needed for a row.
-func (bc *BookCompiler) calculateRowHeight(row []string, colWidth float64) float64 {
-	maxHeight := tableLineHeight
+// calculateRowHeight estimates the height needed for a row from the
+// plain text of its cells in the row's font, including forced line
+// breaks.
+func (bc *BookCompiler) calculateRowHeight(row []*html.Node, colWidth, lineHeight float64, font TextStyle) float64 {
+	maxHeight := lineHeight
+	textWidth := colWidth - 2*bc.pdf.GetCellMargin()
 
 	for _, cell := range row {
-		lines := bc.SplitText(cell, colWidth)
-		height := float64(len(lines)) * tableLineHeight
+		lineCount := 0
+		for _, line := range cellLines(cell) {
+			lineCount += int(math.Max(1, float64(len(bc.SplitTextInFont(bc.cleanText(line), textWidth, font)))))
+		}
+		height := float64(lineCount) * lineHeight
 		if height > maxHeight {
 			maxHeight = height
 		}
@@ -242,18 +538,186 @@ func (bc *BookCompiler) calculateRowHeight(row []string, colWidth float64) float
 	return maxHeight
 }
 
-// renderTableRow renders a single row with specified dimensions.
-func (bc *BookCompiler) renderTableRow(row []string, colWidth, rowHeight float64) error {
-	y := bc.pdf.GetY()
-	x := bc.pdf.GetX()
+// tableFamily returns the font family of table text: the loaded table
+// style's font, the configured table font, or the body text font.
+func (bc *BookCompiler) tableFamily() string {
+	family := bc.tableFont
+	if family == "" {
+		family = bc.textFont
+	}
+	return bc.styleFor("table", TextStyle{FontFamily: family}).FontFamily
+}
+
+// tableSize returns the unscaled font size of table text: the loaded
+// table style's size, or the configured table font size.
+func (bc *BookCompiler) tableSize() float64 {
+	return bc.styleFor("table", TextStyle{Size: bc.tableTextSize}).Size
+}
+
+// renderCellContent renders the content of a table cell. Paragraphs and
+// other block children are rendered as inline text, each starting on a
+// new line, as does inline content that follows them; <br> breaks lines
+// as elsewhere.
+//
+// Parameters:
+//   - n: Cell element, or a block within it. Nil renders nothing.
+//
+// Returns:
+//   - error: Any errors rendering the content
+func (bc *BookCompiler) renderCellContent(n *html.Node) error {
+	if n == nil {
+		return nil
+	}
+
+	wrote, afterBlock := false, false
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode && strings.TrimSpace(c.Data) == "" {
+			if wrote && !afterBlock {
+				bc.writeText(c.Data)
+			}
+			continue
+		}
+
+		block := c.Type == html.ElementNode && !isInline(c)
+		if wrote && (block || afterBlock) {
+			bc.pdf.Ln(bc.lineHeight())
+		}
+
+		var err error
+		if block {
+			err = bc.renderCellContent(c)
+		} else {
+			err = bc.renderNode(c)
+		}
+		if err != nil {
+			return err
+		}
+		wrote, afterBlock = true, block
+	}
+	return nil
+}
+
+// cellLines returns the plain text of a table cell split at its forced
+// line breaks: <br> elements and block boundaries.
+//
+// Parameters:
+//   - n: Cell element. Nil returns no lines.
+//
+// Returns:
+//   - []string: The text of each forced line
+func cellLines(n *html.Node) []string {
+	var text strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.Type == html.TextNode:
+				text.WriteString(c.Data)
+			case c.Type != html.ElementNode:
+			case c.Data == "br":
+				text.WriteString("\n")
+			case isInline(c):
+				walk(c)
+			default:
+				text.WriteString("\n")
+				walk(c)
+				text.WriteString("\n")
+			}
+		}
+	}
+	if n != nil {
+		walk(n)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(text.String(), "\n") {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	// Block boundaries add blank lines at the edges and between blocks
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// renderTableRow renders a single row. Each cell's content goes through
+// the normal inline rendering path, so emphasis, code, and links are kept,
+// with the margins narrowed to the cell so text wraps within it. The row
+// moves to a new page first if its estimated height does not fit, and
+// borders are drawn in the configured TableBorder style once the tallest
+// cell is known.
+//
+// Parameters:
+//   - row: Cell elements; nil cells are left empty
+//   - colWidth: Width of each column in millimeters
+//   - fontSize: Font size of the cell text in points; the line height
+//     scales with it from tableLineHeight at tableFontSize
+//   - style: Base font style of the row ("" or "B")
+//   - fill: Optional background color of the row
+//
+// Returns:
+//   - error: Any errors rendering the cell contents
+func (bc *BookCompiler) renderTableRow(row []*html.Node, colWidth, fontSize float64, style string, fill *rgbColor) error {
+	family := bc.tableFamily()
+	lineHeight := tableLineHeight * fontSize / tableFontSize
+	pageWidth, pageHeight := bc.pdf.GetPageSize()
+	left, top, right, _ := bc.pdf.GetMargins()
+	autoBreak, bottom := bc.pdf.GetAutoPageBreak()
+
+	font := TextStyle{FontFamily: family, Style: style, Size: fontSize}
+	estimate := bc.calculateRowHeight(row, colWidth, lineHeight, font)
+	if y := bc.pdf.GetY(); y+estimate > pageHeight-bottom && y > top {
+		bc.pdf.AddPage()
+	}
+
+	x, y := bc.pdf.GetX(), bc.pdf.GetY()
+	rowBottom := y + lineHeight
+
+	bc.pdf.SetAutoPageBreak(false, bottom)
+	bc.cellLineHeight = lineHeight
+	defer func() {
+		bc.cellLineHeight = 0
+		bc.pdf.SetLeftMargin(left)
+		bc.pdf.SetRightMargin(right)
+		bc.pdf.SetAutoPageBreak(autoBreak, bottom)
+	}()
 
 	for i, cell := range row {
 		cellX := x + float64(i)*colWidth
-		bc.pdf.Rect(cellX, y, colWidth, rowHeight, "D")
-		bc.pdf.MultiCell(colWidth, tableLineHeight, cell, "0", "L", false)
-		bc.pdf.SetXY(cellX+colWidth, y)
+		bc.pdf.SetLeftMargin(cellX)
+		bc.pdf.SetRightMargin(pageWidth - cellX - colWidth)
+		bc.pdf.SetXY(cellX, y)
+		bc.setFont(family, style, fontSize)
+		if err := bc.renderCellContent(cell); err != nil {
+			return err
+		}
+		if cellBottom := bc.pdf.GetY() + lineHeight; cellBottom > rowBottom {
+			rowBottom = cellBottom
+		}
+	}
+
+	width := colWidth * float64(len(row))
+	if fill != nil {
+		// Multiply keeps the text drawn above readable
+		bc.pdf.SetAlpha(1, "Multiply")
+		bc.pdf.SetFillColor(fill.r, fill.g, fill.b)
+		bc.pdf.Rect(x, y, width, rowBottom-y, "F")
+		bc.pdf.SetAlpha(1, "Normal")
+	}
+	switch bc.tableBorderStyle {
+	case TableBorderHorizontal:
+		bc.pdf.Line(x, y, x+width, y)
+		bc.pdf.Line(x, rowBottom, x+width, rowBottom)
+	case TableBorderNone:
+	default:
+		for i := range row {
+			bc.pdf.Rect(x+float64(i)*colWidth, y, colWidth, rowBottom-y, "D")
+		}
 	}
-	bc.pdf.Ln(rowHeight)
+	bc.pdf.SetXY(left, rowBottom)
 
 	return nil
 }
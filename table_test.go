@@ -0,0 +1,245 @@
+package bookie
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// newTestCompiler returns a compiler with an initialized PDF, a page, and
+// the body font set, ready for measuring and rendering.
+func newTestCompiler(t *testing.T) *BookCompiler {
+	t.Helper()
+	bc := NewBookCompiler(t.TempDir(), "")
+	bc.initializePDF()
+	bc.pdf.AddPage()
+	bc.setFont(bc.textFont, fontStyleNormal, defaultFontSize)
+	return bc
+}
+
+// parseMarkdown converts markdown to HTML with the compiler's settings and
+// parses it.
+func parseMarkdown(t *testing.T, bc *BookCompiler, md string) *html.Node {
+	t.Helper()
+	doc, err := html.Parse(bytes.NewReader(bc.markdownToHTML([]byte(md))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// elementsByTag returns the elements with the given tag in document order.
+func elementsByTag(n *html.Node, tag string) []*html.Node {
+	var found []*html.Node
+	if n.Type == html.ElementNode && n.Data == tag {
+		found = append(found, n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		found = append(found, elementsByTag(c, tag)...)
+	}
+	return found
+}
+
+func TestTableCaptionBetweenTables(t *testing.T) {
+	bc := NewBookCompiler(t.TempDir(), "")
+	doc := parseMarkdown(t, bc, "| a |\n|---|\n| 1 |\n\nTable: Between\n\n| b |\n|---|\n| 2 |\n")
+
+	tables := elementsByTag(doc, "table")
+	if len(tables) != 2 {
+		t.Fatalf("parsed %d tables, want 2", len(tables))
+	}
+	if caption := markdownTableCaption(tables[0]); caption != "" {
+		t.Errorf("first table caption = %q, want none", caption)
+	}
+	if caption := markdownTableCaption(tables[1]); caption != "Between" {
+		t.Errorf("second table caption = %q, want %q", caption, "Between")
+	}
+	if p := elementsByTag(doc, "p")[0]; !isTableCaption(p) {
+		t.Error("caption paragraph is not recognized as a caption")
+	}
+}
+
+func TestSplitTextBreaksLongWord(t *testing.T) {
+	bc := newTestCompiler(t)
+	word := strings.Repeat("abcdefghij", 20)
+	const width = 30.0
+
+	lines := bc.SplitText(word, width)
+	if len(lines) < 2 {
+		t.Fatalf("SplitText returned %d lines, want several", len(lines))
+	}
+	for i, line := range lines {
+		if w := bc.pdf.GetStringWidth(line); w > width {
+			t.Errorf("line %d %q is %.2fmm wide, want at most %.2fmm", i, line, w, width)
+		}
+	}
+	if joined := strings.Join(lines, ""); joined != word {
+		t.Errorf("lines join to %q, want %q", joined, word)
+	}
+}
+
+func TestFitRunesMeasuresWholeWord(t *testing.T) {
+	bc := newTestCompiler(t)
+	width := bc.pdf.GetStringWidth("abc") + 0.01
+
+	if got := bc.fitRunes("abcd", width); got != "abc" {
+		t.Errorf("fitRunes = %q, want %q", got, "abc")
+	}
+	if got := bc.fitRunes("w", 0.01); got != "w" {
+		t.Errorf("fitRunes = %q, want at least the first rune", got)
+	}
+}
+
+// wideTableMarkdown returns a markdown table with a "Key" column followed
+// by columns "C2" through "C<columns>", and two rows.
+func wideTableMarkdown(columns int) string {
+	var md strings.Builder
+	md.WriteString("| Key |")
+	for c := 2; c <= columns; c++ {
+		fmt.Fprintf(&md, " C%d |", c)
+	}
+	md.WriteString("\n|" + strings.Repeat("---|", columns) + "\n")
+	for r := 1; r <= 2; r++ {
+		fmt.Fprintf(&md, "| k%d |", r)
+		for c := 2; c <= columns; c++ {
+			fmt.Fprintf(&md, " v%d.%d |", r, c)
+		}
+		md.WriteString("\n")
+	}
+	return md.String()
+}
+
+func TestSplitTableRepeatsKeyColumn(t *testing.T) {
+	bc, recording := newRecordedCompiler(t, t.TempDir())
+	bc.SetWideTableMode(WideTableSplit)
+	renderRecorded(t, bc, nil, wideTableMarkdown(10))
+	pdf := recording()
+
+	keyPages := make(map[int]bool)
+	for _, call := range pdf.textCalls() {
+		if strings.TrimSpace(call.text) == "Key" {
+			keyPages[call.page] = true
+		}
+	}
+	if len(keyPages) < 2 {
+		t.Fatalf("key column drawn on %d pages, want it repeated on several", len(keyPages))
+	}
+	for c := 2; c <= 10; c++ {
+		header := fmt.Sprintf("C%d", c)
+		page := pdf.pageOf(header)
+		if page == 0 {
+			t.Errorf("column %s not drawn", header)
+			continue
+		}
+		if !keyPages[page] {
+			t.Errorf("column %s on page %d without the key column", header, page)
+		}
+	}
+	if first, last := pdf.pageOf("C2"), pdf.pageOf("C10"); last <= first {
+		t.Errorf("last column on page %d, first on %d, want the table split across pages", last, first)
+	}
+}
+
+func TestRotatedTablePages(t *testing.T) {
+	tests := []struct {
+		name      string
+		snippets  []string
+		wantPage  int // Page of the table
+		wantPages int
+	}{
+		{"after text", []string{"Opening text.", wideTableMarkdown(10)}, 2, 3},
+		{"at the top of a page", []string{wideTableMarkdown(10)}, 1, 1},
+	}
+	for _, tt := range tests {
+		bc, recording := newRecordedCompiler(t, t.TempDir())
+		bc.SetWideTableMode(WideTableRotate)
+		renderRecorded(t, bc, nil, tt.snippets...)
+		pdf := recording()
+
+		if page := pdf.pageOf("C10"); page != tt.wantPage {
+			t.Errorf("%s: table on page %d, want %d", tt.name, page, tt.wantPage)
+		}
+		if pages := pdf.PageNo(); pages != tt.wantPages {
+			t.Errorf("%s: %d pages, want %d", tt.name, pages, tt.wantPages)
+		}
+	}
+}
+
+// cellTexts returns the text of each cell in a row.
+func cellTexts(cells []*html.Node) []string {
+	var texts []string
+	for _, cell := range cells {
+		texts = append(texts, strings.TrimSpace(getTextContent(cell)))
+	}
+	return texts
+}
+
+func TestParseTableStructureFromMarkdown(t *testing.T) {
+	bc := NewBookCompiler(t.TempDir(), "")
+	doc := parseMarkdown(t, bc, "| Name | Age |\n|------|-----|\n| Ann | 31 |\n| Bob | 42 |\n")
+	tables := elementsByTag(doc, "table")
+	if len(tables) != 1 || len(elementsByTag(tables[0], "tbody")) != 1 {
+		t.Fatalf("blackfriday output has %d tables, want one with a tbody", len(tables))
+	}
+
+	headers, rows, footers, err := bc.parseTableStructure(tables[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(cellTexts(headers), ","); got != "Name,Age" {
+		t.Errorf("headers = %q, want Name,Age", got)
+	}
+	if len(rows) != 2 || strings.Join(cellTexts(rows[1]), ",") != "Bob,42" {
+		t.Errorf("rows = %d, want 2 ending with Bob,42", len(rows))
+	}
+	if len(footers) != 0 {
+		t.Errorf("footers = %d, want none", len(footers))
+	}
+}
+
+func TestParseTableStructureWithFooter(t *testing.T) {
+	bc := NewBookCompiler(t.TempDir(), "")
+	doc := parseMarkdown(t, bc, "<table>\n<thead><tr><th>Item</th><th>Cost</th></tr></thead>\n"+
+		"<tbody><tr><td>Tea</td><td>3</td></tr></tbody>\n"+
+		"<tfoot><tr><td>Total</td><td>3</td></tr></tfoot>\n</table>\n")
+
+	headers, rows, footers, err := bc.parseTableStructure(elementsByTag(doc, "table")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(cellTexts(headers), ","); got != "Item,Cost" {
+		t.Errorf("headers = %q, want Item,Cost", got)
+	}
+	if len(rows) != 1 || strings.Join(cellTexts(rows[0]), ",") != "Tea,3" {
+		t.Errorf("rows = %d, want the single body row", len(rows))
+	}
+	if len(footers) != 1 || strings.Join(cellTexts(footers[0]), ",") != "Total,3" {
+		t.Errorf("footers = %d, want the Total row", len(footers))
+	}
+}
+
+func TestRenderMarkdownTable(t *testing.T) {
+	bc, recording := newRecordedCompiler(t, t.TempDir())
+	renderRecorded(t, bc, nil, "| Name | Age |\n|------|-----|\n| Ann | 31 |\n")
+	pdf := recording()
+	for _, text := range []string{"Name", "Age", "Ann", "31"} {
+		if pdf.pageOf(text) == 0 {
+			t.Errorf("table cell %q not drawn", text)
+		}
+	}
+}
+
+func TestTableFontInvalidSizeKeepsDefault(t *testing.T) {
+	bc, recording := newRecordedCompiler(t, t.TempDir())
+	bc.SetTableFont("", 0)
+	renderRecorded(t, bc, nil, "| Name | Age |\n|------|-----|\n| Ann | 31 |\n")
+
+	for _, call := range recording().textCalls() {
+		if call.text == "Ann" && call.size != tableFontSize {
+			t.Errorf("table cell drawn at %.1fpt, want %.1fpt", call.size, tableFontSize)
+		}
+	}
+}
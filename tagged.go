@@ -0,0 +1,96 @@
+package bookie
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// defaultLanguage is the document language recorded in tagged PDFs.
+const defaultLanguage = "en"
+
+// xmpLanguageTemplate is the XMP metadata packet recording the document
+// language as dc:language. gofpdf cannot write the catalog /Lang entry,
+// so the language is carried in the metadata stream instead.
+const xmpLanguageTemplate = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:language><rdf:Bag><rdf:li>%s</rdf:li></rdf:Bag></dc:language>
+</rdf:Description>
+</rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+// writeLanguageMetadata records the document language in the PDF's XMP
+// metadata, using defaultLanguage if none was set.
+func (bc *BookCompiler) writeLanguageMetadata() {
+	lang := bc.language
+	if lang == "" {
+		lang = defaultLanguage
+	}
+	lang = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(lang)
+	bc.pdf.SetXmpMetadata([]byte(fmt.Sprintf(xmpLanguageTemplate, lang)))
+}
+
+// beginMarkedContent opens a marked-content sequence with the given
+// structure tag on the current page. It does nothing unless tagged output
+// is enabled.
+//
+// Parameters:
+//   - tag: Standard structure type (e.g., "H1", "Figure")
+//   - alt: Alternate description for the content; empty for none
+//
+// Returns:
+//   - int: Page the sequence was opened on, to pass to endMarkedContent
+func (bc *BookCompiler) beginMarkedContent(tag, alt string) int {
+	if !bc.taggedPDF {
+		return 0
+	}
+	if alt == "" {
+		bc.pdf.RawWriteStr(fmt.Sprintf("/%s BMC", tag))
+	} else {
+		bc.pdf.RawWriteStr(fmt.Sprintf("/%s <</Alt %s>> BDC", tag, pdfTextString(alt)))
+	}
+	return bc.pdf.PageNo()
+}
+
+// endMarkedContent closes a sequence opened by beginMarkedContent. Marked
+// content cannot span content streams, so if the content broke onto a new
+// page the sequence is closed at the end of the page it started on.
+//
+// Parameters:
+//   - page: Page returned by beginMarkedContent
+func (bc *BookCompiler) endMarkedContent(page int) {
+	if !bc.taggedPDF {
+		return
+	}
+	current := bc.pdf.PageNo()
+	if page == current {
+		bc.pdf.RawWriteStr("EMC")
+		return
+	}
+	x, y := bc.pdf.GetXY()
+	bc.pdf.SetPage(page)
+	bc.pdf.RawWriteStr("EMC")
+	bc.pdf.SetPage(current)
+	bc.pdf.SetXY(x, y)
+}
+
+// pdfTextString encodes s as a UTF-16BE hexadecimal PDF text string, which
+// represents any Unicode text without escaping.
+//
+// Parameters:
+//   - s: Text to encode
+//
+// Returns:
+//   - string: The hex string including the byte order mark (e.g., "<FEFF0041>")
+func pdfTextString(s string) string {
+	var hex strings.Builder
+	hex.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&hex, "%04X", unit)
+	}
+	hex.WriteString(">")
+	return hex.String()
+}
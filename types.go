@@ -4,7 +4,13 @@
 // converting structured markdown content into professionally formatted PDF documents.
 package bookie
 
-import "github.com/jung-kurt/gofpdf"
+import (
+	"io/fs"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/russross/blackfriday/v2"
+)
 
 // Default page settings in millimeters (A4)
 const (
@@ -27,6 +33,41 @@ const (
 	AlignRight  = "R"
 )
 
+// FigureNumbering selects how figure and table captions are numbered.
+type FigureNumbering int
+
+// Figure numbering modes
+const (
+	// NumberingNone leaves captions unnumbered
+	NumberingNone FigureNumbering = iota
+
+	// NumberingContinuous numbers figures and tables across the whole book
+	// (e.g., "Figure 7")
+	NumberingContinuous
+
+	// NumberingPerChapter restarts numbering in each chapter and prefixes
+	// the chapter number (e.g., "Figure 3.2")
+	NumberingPerChapter
+)
+
+// WideTableMode selects how tables with too many columns for the content
+// width are rendered.
+type WideTableMode int
+
+// Wide table modes
+const (
+	// WideTableScaleToFit shrinks the font and column widths to fit the page
+	WideTableScaleToFit WideTableMode = iota
+
+	// WideTableSplit renders the table in horizontal slices on successive
+	// pages, repeating the first column as a key in every slice
+	WideTableSplit
+
+	// WideTableRotate renders the table on its own landscape page, or
+	// scales it to fit when it starts a page
+	WideTableRotate
+)
+
 // BookCompiler handles the conversion of markdown files into structured PDF documents.
 // It provides functionality for organizing content into chapters, generating a table
 // of contents, and applying consistent styling throughout the document.
@@ -50,9 +91,43 @@ type BookCompiler struct {
 	// Must be a writable path.
 	OutputPath string
 
-	// pdf is the underlying PDF generator instance.
-	// Initialized during compilation.
-	pdf *gofpdf.Fpdf
+	// sources lists additional directories whose chapters are merged
+	// with those in RootDir.
+	sources []string
+
+	// chapterRangeFrom and chapterRangeTo limit compilation to the
+	// episodes numbered between them, inclusive; zero leaves that end
+	// open.
+	chapterRangeFrom int
+	chapterRangeTo   int
+
+	// ignorePatterns are gitignore-style patterns of paths, relative to
+	// each source directory, left out of the book.
+	ignorePatterns []string
+
+	// ignoreRoot is the source directory being scanned and ignoreRules
+	// the rules applying to it, from ignorePatterns and its .bookieignore.
+	ignoreRoot  string
+	ignoreRules []ignoreRule
+
+	// fsys, when set, is the filesystem book content is read from
+	// instead of the operating system's.
+	fsys fs.FS
+
+	// contentChapters are chapters supplied in memory, which replace the
+	// directory scan. contents holds their files and images by virtual
+	// path; contentImages maps image names to those paths.
+	contentChapters []Chapter
+	contents        map[string][]byte
+	contentImages   map[string]string
+
+	// pdf is the underlying PDF generator instance, a *gofpdf.Fpdf
+	// except in tests. Initialized during compilation.
+	pdf pdfWriter
+
+	// newPDF creates each document pdf is set to; newGofpdf unless a
+	// test injects a recording writer.
+	newPDF func() pdfWriter
 
 	// imageCache tracks processed images to prevent duplicate processing.
 	// Keys are image file paths, values indicate processing status.
@@ -66,12 +141,42 @@ type BookCompiler struct {
 	// Must be a valid font name supported by gofpdf.
 	textFont string
 
+	// generateToCPage controls whether a table of contents page is
+	// rendered at the start of the book.
+	generateToCPage bool
+
+	// tocSummaries renders a chapter summary beneath each chapter's
+	// ToC entry.
+	tocSummaries bool
+
+	// tocTitleStyle is the font and alignment of the ToC heading; an
+	// empty FontFamily uses the chapter font.
+	tocTitleStyle TextStyle
+
 	// toc holds the table of contents entries in document order.
 	toc []ToCEntry
 
 	// pageNumbers controls whether page numbers are rendered.
 	pageNumbers bool
 
+	// pageNumberFormat is the page number text; the first %d is replaced by
+	// the page number and an optional second %d by the total page count.
+	pageNumberFormat string
+
+	// pageNumberStyle holds the page number font and horizontal alignment.
+	pageNumberStyle TextStyle
+
+	// frontMatterNumbering is FrontMatterArabic or FrontMatterRoman.
+	frontMatterNumbering string
+
+	// bodyStartPage is the physical page on which the first chapter opens,
+	// or 0 while rendering front matter.
+	bodyStartPage int
+
+	// tocBodyStartPage is the bodyStartPage recorded during the first pass,
+	// used to label the page numbers listed in the table of contents.
+	tocBodyStartPage int
+
 	// tocTitle specifies the heading text for the table of contents.
 	tocTitle string
 
@@ -94,8 +199,501 @@ type BookCompiler struct {
 	// currentFile tracks the markdown file being processed.
 	currentFile string
 
-	// currentChapter tracks the chapter being processed.
+	// currentChapter tracks the chapter or matter section being processed.
 	currentChapter interface{}
+
+	// figureNumbering controls the "Figure N"/"Table N" caption prefixes.
+	figureNumbering FigureNumbering
+
+	// chapterNumber is the episode number of the chapter being processed.
+	chapterNumber int
+
+	// figureCount and tableCount are the running caption counters.
+	figureCount int
+	tableCount  int
+
+	// captionLabels maps element ids to their numbered labels
+	// (e.g., "fig-arch" -> "Figure 2") for use as cross-reference targets.
+	captionLabels map[string]string
+
+	// wideTableMode controls rendering of tables that exceed the page width.
+	wideTableMode WideTableMode
+
+	// collectStats enables recording of per-phase timings.
+	collectStats bool
+
+	// timings holds the phase timings of the last compilation.
+	timings PhaseTimings
+
+	// allowRemoteImages enables downloading of http(s) image sources.
+	allowRemoteImages bool
+
+	// remoteImages maps downloaded image URLs to their temporary files.
+	remoteImages map[string]string
+
+	// headerRule controls whether a rule is drawn across the page header.
+	headerRule bool
+
+	// headerRuleWeight is the header rule line width in millimeters.
+	headerRuleWeight float64
+
+	// headerRuleColor is the header rule color.
+	headerRuleColor rgbColor
+
+	// watermarkText is stamped diagonally across every page when set.
+	watermarkText string
+
+	// watermarkImage is the path of an image stamped on every page when set.
+	watermarkImage string
+
+	// watermarkOpacity is the watermark alpha, from 0 (invisible) to 1.
+	watermarkOpacity float64
+
+	// listMarkerColor is the color of bullets and list numbers.
+	// Nil keeps the current text color.
+	listMarkerColor *rgbColor
+
+	// runningHeaders enables the book and chapter title page headers.
+	runningHeaders bool
+
+	// bookTitle is the book title shown in running headers.
+	bookTitle string
+
+	// headerVerso and headerRecto are the running header templates for
+	// even and odd pages. See SetHeaderTemplates for placeholders.
+	headerVerso string
+	headerRecto string
+
+	// chapterStartPage is the page number on which the current chapter opens.
+	chapterStartPage int
+
+	// font is the most recently selected text font.
+	font TextState
+
+	// glyphFallbackFont is the UTF-8 font family used for runes the
+	// primary core fonts cannot encode. Empty disables fallback.
+	glyphFallbackFont string
+
+	// glyphFallbackPath is the TrueType file of the glyph fallback font.
+	glyphFallbackPath string
+
+	// preserveNewlines renders single newlines inside paragraphs as line
+	// breaks instead of collapsing them into spaces.
+	preserveNewlines bool
+
+	// frontMatter lists sections rendered between the ToC and the chapters.
+	frontMatter []matterSection
+
+	// backMatter lists sections rendered after the last chapter.
+	backMatter []matterSection
+
+	// appendixCount is the number of lettered appendices in backMatter.
+	appendixCount int
+
+	// frontMatterInToC controls whether front matter appears in the ToC.
+	frontMatterInToC bool
+
+	// coverPage is the page holding the current chapter's cover image.
+	coverPage int
+
+	// indexTitle is the title of the generated index section.
+	indexTitle string
+
+	// indexTerms maps marked index terms to the pages they appear on.
+	indexTerms map[string][]int
+
+	// hasIndexTerms records whether the first pass found index markers.
+	hasIndexTerms bool
+
+	// knownAnchors holds the anchor names found during the first pass.
+	knownAnchors map[string]bool
+
+	// anchorLinks maps anchor names to gofpdf internal link identifiers.
+	anchorLinks map[string]int
+
+	// linkID is the internal link applied to text being written, or 0.
+	linkID int
+
+	// linkURL is the external URL applied to text being written, or empty.
+	linkURL string
+
+	// textColor is the body text color.
+	textColor rgbColor
+
+	// headingColor is the color of headings and chapter titles.
+	headingColor rgbColor
+
+	// backgroundColor fills every page when set.
+	backgroundColor *rgbColor
+
+	// linkColor is the text color of external links.
+	linkColor rgbColor
+
+	// linkUnderline underlines external link text.
+	linkUnderline bool
+
+	// smartPunctuation enables blackfriday's smartypants conversion of
+	// quotes, dashes, and fractions.
+	smartPunctuation bool
+
+	// hyphenation is the language whose patterns break words at line
+	// ends; empty disables hyphenation.
+	hyphenation string
+
+	// hyphenationPatterns holds loaded and built-in hyphenators by
+	// language.
+	hyphenationPatterns map[string]*hyphenator
+
+	// markdownExtensions holds the blackfriday extension flags used to
+	// parse chapter markdown.
+	markdownExtensions blackfriday.Extensions
+
+	// paragraphIndent is the first-line indent of body paragraphs in
+	// millimeters; zero disables indentation.
+	paragraphIndent float64
+
+	// paragraphSpacing is the vertical gap before each paragraph in
+	// millimeters.
+	paragraphSpacing float64
+
+	// lineSpacing multiplies the body text line height (1.0 = single,
+	// 2.0 = double spacing).
+	lineSpacing float64
+
+	// progress is called after each markdown file is rendered;
+	// progressDone and progressTotal count files for the current compilation.
+	progress                    ProgressFunc
+	progressDone, progressTotal int
+
+	// headingStyles holds the font and spacing of each heading level (1-6).
+	headingStyles map[int]headingStyle
+
+	// pageBreakBefore lists the heading levels (1-6) that always start
+	// a new page.
+	pageBreakBefore map[int]bool
+
+	// keepWithNextLines is the number of body lines that must fit below
+	// a heading on the same page; otherwise the heading moves to the next page.
+	keepWithNextLines int
+
+	// widowOrphanControl keeps single paragraph lines from being
+	// stranded across page breaks.
+	widowOrphanControl bool
+
+	// pageBreakThreshold is the space in millimeters a block needs above
+	// the bottom margin to start on the current page; zero derives it
+	// from the line height.
+	pageBreakThreshold float64
+
+	// kerning enables pair kerning for chapter and section titles.
+	kerning bool
+
+	// continuationNote appends a "(continued)" note to the chapter title in
+	// running headers after the chapter's opening page.
+	continuationNote bool
+
+	// dropCaps renders the first letter of each chapter's opening
+	// paragraph as a drop cap. dropCapPending is set until that paragraph
+	// is reached; while a drop cap is being wrapped, dropCapBottom is the
+	// Y position below it and dropCapMargin the normal left margin.
+	dropCaps, dropCapPending     bool
+	dropCapBottom, dropCapMargin float64
+
+	// chapterOpening is set from the start of a chapter or section until
+	// its first content is rendered, so that a heading opening it stays on
+	// the opening page.
+	chapterOpening bool
+
+	// pageBreakPending is set by a manual page break until the next
+	// content is rendered, when the new page is added.
+	pageBreakPending bool
+
+	// bookmarkDepth is one more than the outline level of the last PDF
+	// bookmark added, the deepest level the next bookmark may use.
+	bookmarkDepth int
+
+	// headingCounts counts the h2-h6 headings of the appendix being
+	// collected or rendered, indexed by level, to number them.
+	headingCounts [7]int
+
+	// blockquoteBarWidth and blockquoteBarColor style the vertical bar
+	// beside blockquotes; a zero width disables it. blockquoteBackground,
+	// when set, tints the quote's background.
+	blockquoteBarWidth   float64
+	blockquoteBarColor   rgbColor
+	blockquoteBackground *rgbColor
+
+	// highlightColor fills the background of <mark> text; highlighting is
+	// set while such text is rendered.
+	highlightColor rgbColor
+	highlighting   bool
+
+	// tableFont and tableTextSize set the table text; an empty family
+	// means the body text font.
+	tableFont     string
+	tableTextSize float64
+
+	// tableBorderStyle is TableBorderGrid, TableBorderHorizontal, or
+	// TableBorderNone.
+	tableBorderStyle string
+
+	// tableZebra fills every second table data row with tableZebraColor.
+	tableZebra      bool
+	tableZebraColor rgbColor
+
+	// taggedPDF marks headings and figures as structured content.
+	taggedPDF bool
+
+	// language is the document language (e.g., "en-US"); empty means
+	// defaultLanguage in tagged PDFs and unset otherwise.
+	language string
+
+	// imageDPI is the resolution images are downsampled to at their
+	// display size; zero embeds images unchanged.
+	imageDPI int
+
+	// protection, when set, encrypts the PDF.
+	protection *protection
+
+	// compression compresses page content streams.
+	compression bool
+
+	// deterministic fixes the PDF dates and resource order so identical
+	// input produces identical output.
+	deterministic bool
+
+	// pageLayout and initialZoom control how viewers open the PDF.
+	pageLayout  string
+	initialZoom string
+
+	// cellLineHeight overrides the line height while a table cell is
+	// rendered; zero outside tables.
+	cellLineHeight float64
+
+	// altTextWarnings logs a warning for each image without alt text;
+	// missingAltText lists such images ("file: src") from the last compile.
+	altTextWarnings bool
+	missingAltText  []string
+
+	// continueOnError draws a placeholder for an image that cannot be
+	// found or loaded instead of failing; unresolvedImages lists such
+	// images ("file: src") from the last compile.
+	continueOnError  bool
+	unresolvedImages []string
+
+	// elementStyles holds the styles loaded with LoadStyles, keyed by
+	// element type ("h1", "p", "table", ...).
+	elementStyles map[string]elementStyle
+
+	// chapterStyles holds the styles loaded with LoadChapterStyles, keyed
+	// by chapter directory name; styleStack holds the overrides in effect
+	// for the chapter being rendered, applied on top of elementStyles.
+	chapterStyles map[string]map[string]elementStyle
+	styleStack    []map[string]elementStyle
+
+	// unsupportedGlyphs records runes already warned about as dropped
+	// during the current compile.
+	unsupportedGlyphs map[rune]bool
+
+	// math enables $...$ and $$...$$ math; mathRenderer, when set,
+	// converts it to images.
+	math         bool
+	mathRenderer MathRenderer
+
+	// fenceRenderers maps code block languages (e.g., "mermaid") to the
+	// functions that convert such blocks to images.
+	fenceRenderers map[string]FenceRenderer
+
+	// svgRasterizer converts SVG images to JPEG for embedding.
+	svgRasterizer SVGRasterizer
+
+	// renderedImages caches the images made by math and fence renderers
+	// and the SVG rasterizer, keyed by renderer and source.
+	renderedImages map[string]string
+
+	// thematicBreakStyle is ThematicBreakRule, ThematicBreakAsterisks,
+	// or ThematicBreakSpace.
+	thematicBreakStyle string
+
+	// textDirection is TextDirectionLTR or TextDirectionRTL.
+	textDirection string
+
+	// fileSeparator is FileSeparatorSpace, FileSeparatorRule,
+	// FileSeparatorPageBreak, or FileSeparatorNone.
+	fileSeparator string
+
+	// chapterTitlePosition is ChapterTitleTop, ChapterTitleUpperThird,
+	// or ChapterTitleCentered.
+	chapterTitlePosition string
+
+	// chapterTitleRule draws a rule below chapter titles.
+	chapterTitleRule bool
+
+	// chapterSubtitles shows the H1 a chapter's first file opens with
+	// below the chapter title, in place of the heading itself.
+	chapterSubtitles bool
+
+	// skipLeadingHeading is set while the next file rendered opens with
+	// the H1 shown as the chapter subtitle.
+	skipLeadingHeading bool
+
+	// chapterLabel is drawn above chapter titles with "{n}" replaced by
+	// the chapter number; empty for none.
+	chapterLabel string
+}
+
+// Front matter page numbering styles
+const (
+	// FrontMatterArabic numbers all pages sequentially with Arabic numerals
+	FrontMatterArabic = "arabic"
+
+	// FrontMatterRoman numbers pages before the first chapter with
+	// lowercase Roman numerals and restarts Arabic numbering at 1 when
+	// the first chapter begins
+	FrontMatterRoman = "roman"
+)
+
+// Thematic break styles for markdown horizontal rules ("---")
+const (
+	// ThematicBreakRule draws a horizontal line (default)
+	ThematicBreakRule = "rule"
+
+	// ThematicBreakAsterisks draws a centered "* * *" scene break
+	ThematicBreakAsterisks = "asterisks"
+
+	// ThematicBreakSpace leaves blank vertical space
+	ThematicBreakSpace = "space"
+)
+
+// Chapter title positions for SetChapterTitleLayout
+const (
+	// ChapterTitleTop places the title near the top of the page (default)
+	ChapterTitleTop = "top"
+
+	// ChapterTitleUpperThird places the title a third of the way down
+	ChapterTitleUpperThird = "upper-third"
+
+	// ChapterTitleCentered centers the title vertically
+	ChapterTitleCentered = "centered"
+)
+
+// Separators between the files of a chapter for SetIntraChapterSeparator
+const (
+	// FileSeparatorSpace leaves two blank lines (default)
+	FileSeparatorSpace = "space"
+
+	// FileSeparatorRule draws a horizontal line
+	FileSeparatorRule = "rule"
+
+	// FileSeparatorPageBreak starts the next file on a new page
+	FileSeparatorPageBreak = "pagebreak"
+
+	// FileSeparatorNone lets the files flow continuously
+	FileSeparatorNone = "none"
+)
+
+// Text directions for SetTextDirection
+const (
+	// TextDirectionLTR lays text out left to right (default)
+	TextDirectionLTR = "ltr"
+
+	// TextDirectionRTL lays text out right to left, for Arabic and Hebrew
+	TextDirectionRTL = "rtl"
+)
+
+// Page layouts for SetPageLayout
+const (
+	// PageLayoutSingle shows one page at a time
+	PageLayoutSingle = "single"
+
+	// PageLayoutContinuous scrolls pages in a single column
+	PageLayoutContinuous = "continuous"
+
+	// PageLayoutTwoColumn scrolls facing pages side by side, with
+	// odd-numbered pages on the right as in a printed book
+	PageLayoutTwoColumn = "two-column"
+
+	// PageLayoutTwoPage shows one spread of facing pages at a time, with
+	// odd-numbered pages on the right
+	PageLayoutTwoPage = "two-page"
+)
+
+// Initial zoom levels for SetInitialZoom
+const (
+	// ZoomFullPage fits the whole page in the window
+	ZoomFullPage = "fullpage"
+
+	// ZoomFullWidth fits the page width to the window
+	ZoomFullWidth = "fullwidth"
+
+	// ZoomActualSize shows pages at 100%
+	ZoomActualSize = "real"
+)
+
+// pageLayoutModes maps page layouts to gofpdf display layout names.
+var pageLayoutModes = map[string]string{
+	PageLayoutSingle:     "SinglePage",
+	PageLayoutContinuous: "OneColumn",
+	PageLayoutTwoColumn:  "TwoColumnRight",
+	PageLayoutTwoPage:    "TwoPageRight",
+}
+
+// Table border styles for SetTableBorderStyle
+const (
+	// TableBorderGrid draws a box around every cell (default)
+	TableBorderGrid = "grid"
+
+	// TableBorderHorizontal draws rules above and below each row only
+	TableBorderHorizontal = "horizontal"
+
+	// TableBorderNone draws no borders
+	TableBorderNone = "none"
+)
+
+// Permissions granted to readers of a protected PDF (see SetProtection)
+const (
+	// PermitPrint allows printing
+	PermitPrint = gofpdf.CnProtectPrint
+
+	// PermitModify allows editing
+	PermitModify = gofpdf.CnProtectModify
+
+	// PermitCopy allows copying text and images
+	PermitCopy = gofpdf.CnProtectCopy
+
+	// PermitAnnotate allows adding annotations and filling forms
+	PermitAnnotate = gofpdf.CnProtectAnnotForms
+)
+
+// protection holds the encryption settings of the output PDF.
+type protection struct {
+	permissions   byte
+	userPassword  string
+	ownerPassword string
+}
+
+// rgbColor is an RGB color with components in the range 0-255.
+type rgbColor struct {
+	r, g, b int
+}
+
+// PhaseTimings records the time spent in each compilation phase.
+// Durations accumulate across both compilation passes.
+type PhaseTimings struct {
+	// Discovery is the time spent scanning for chapters and markdown files
+	Discovery time.Duration
+
+	// Conversion is the time spent converting markdown to HTML
+	Conversion time.Duration
+
+	// Parsing is the time spent parsing the generated HTML
+	Parsing time.Duration
+
+	// Rendering is the time spent rendering parsed content into the PDF
+	Rendering time.Duration
+
+	// Output is the time spent writing the finished PDF file
+	Output time.Duration
 }
 
 // ToCEntry represents a single entry in the table of contents.
@@ -117,6 +715,14 @@ type ToCEntry struct {
 
 	// Link is the internal PDF identifier for creating clickable navigation
 	Link int
+
+	// Anchor is the chapter anchor or heading ID the entry links to,
+	// empty if the entry is not clickable
+	Anchor string
+
+	// Summary is an optional one-line description rendered beneath
+	// chapter entries when ToC summaries are enabled
+	Summary string
 }
 
 // Chapter represents a collection of markdown files forming a logical unit.
@@ -131,6 +737,11 @@ type Chapter struct {
 	// Path is the full filesystem path to the chapter directory
 	Path string
 
+	// Title overrides the title derived from Path when set, as for
+	// chapters added with AddChapterContent or given a "title" in their
+	// front matter
+	Title string
+
 	// Files contains the sorted list of markdown files in this chapter
 	Files []string
 
@@ -138,6 +749,55 @@ type Chapter struct {
 	// Keys are image filenames as referenced in markdown,
 	// values are absolute paths to the image files.
 	Images map[string]string
+
+	// Meta holds the front matter fields declared at the top of the
+	// chapter's first markdown file (e.g., "cover" -> "cover.jpg"),
+	// overridden by those of its chapter.md or _meta.md file, if any.
+	// "title", "author", and "weight" set the chapter title, the author
+	// shown below it, and the chapter's position in the book.
+	Meta map[string]string
+}
+
+// ProgressFunc receives compilation progress: the number of markdown
+// files rendered so far, the total number of files, and the file that was
+// just completed.
+type ProgressFunc func(done, total int, currentFile string)
+
+// MathRenderer converts a LaTeX math expression (without its dollar
+// signs) to a JPEG image and returns the image's path.
+type MathRenderer func(latex string) (imagePath string, err error)
+
+// FenceRenderer converts the content of a fenced code block, such as a
+// mermaid or PlantUML diagram, to a JPEG image and returns the image's
+// path.
+type FenceRenderer func(code string) (imagePath string, err error)
+
+// SVGRasterizer converts an SVG document to a JPEG image and returns the
+// image's path.
+type SVGRasterizer func(svg string) (imagePath string, err error)
+
+// headingStyle describes how one heading level is rendered.
+type headingStyle struct {
+	// text is the heading font; an empty FontFamily uses the chapter font
+	text TextStyle
+
+	// spaceBefore and spaceAfter are the vertical gaps around the heading
+	// in millimeters
+	spaceBefore, spaceAfter float64
+}
+
+// matterSection is an unnumbered, titled section of front or back matter
+// rendered from a single markdown file.
+type matterSection struct {
+	// title is the heading shown on the section's opening page
+	title string
+
+	// path is the markdown file containing the section content
+	path string
+
+	// letter numbers the headings of an appendix (e.g., "A.1"); empty
+	// for other sections
+	letter string
 }
 
 // TextStyle defines visual formatting attributes for text elements.
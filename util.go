@@ -6,6 +6,7 @@ package bookie
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/russross/blackfriday/v2"
@@ -18,6 +19,9 @@ const (
 	jpegExtension = ".jpeg"
 )
 
+// numericEntity matches a numeric character reference such as "&#8212;".
+var numericEntity = regexp.MustCompile(`&#[xX]?[0-9A-Fa-f]+;`)
+
 // getString extracts all text content from a markdown node by walking its tree.
 // It concatenates content from Text nodes while preserving document order and
 // ignoring formatting elements.
@@ -27,7 +31,8 @@ const (
 //     May be nil, in which case an empty string is returned.
 //
 // Returns:
-//   - A string containing the concatenated text from all Text nodes in the tree.
+//   - A string containing the concatenated text from all Text nodes in the tree,
+//     with entities left in the text (e.g., "&mdash;") decoded.
 //
 // Related: blackfriday.Node, blackfriday.WalkStatus
 func getString(node *blackfriday.Node) string {
@@ -41,7 +46,7 @@ func getString(node *blackfriday.Node) string {
 
 	node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
 		if entering && n.Type == blackfriday.Text && n.Literal != nil {
-			result.Write(n.Literal)
+			result.WriteString(html.UnescapeString(string(n.Literal)))
 		}
 		return blackfriday.GoToNext
 	})
@@ -114,6 +119,34 @@ func findParent(n *html.Node, tag string) *html.Node {
 	return nil
 }
 
+// findDescendant locates the first descendant node with a specified HTML tag.
+// The search is depth-first in document order and does not match n itself.
+//
+// Parameters:
+//   - n: The node whose subtree is searched. If nil, returns nil.
+//   - tag: The HTML tag name to search for (e.g., "img", "figcaption").
+//     Empty tag returns nil.
+//
+// Returns:
+//   - The first descendant node matching the tag, or nil if none is found
+//
+// Related: findParent, html.ElementNode
+func findDescendant(n *html.Node, tag string) *html.Node {
+	if n == nil || tag == "" {
+		return nil
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == tag {
+			return c
+		}
+		if found := findDescendant(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
 // countPreviousSiblings counts HTML element nodes that precede the given node.
 // Only considers ElementNode types, ignoring text and comment nodes.
 //
@@ -138,6 +171,56 @@ func countPreviousSiblings(n *html.Node) int {
 	return count
 }
 
+// firstElementChild returns the first child of n that is an element.
+//
+// Parameters:
+//   - n: The node whose children are examined
+//
+// Returns:
+//   - The first element child, or nil if there is none
+func firstElementChild(n *html.Node) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// previousElement returns the nearest preceding sibling that is an
+// element, skipping text and comment nodes.
+//
+// Parameters:
+//   - n: The node whose siblings are examined
+//
+// Returns:
+//   - The previous element sibling, or nil if there is none
+func previousElement(n *html.Node) *html.Node {
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+// nextElement returns the nearest following sibling that is an element,
+// skipping text and comment nodes.
+//
+// Parameters:
+//   - n: The node whose siblings are examined
+//
+// Returns:
+//   - The next element sibling, or nil if there is none
+func nextElement(n *html.Node) *html.Node {
+	for s := n.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
 // getAttr retrieves an attribute value from an HTML node by key.
 // Commonly used for extracting href, src, class, and other HTML attributes.
 //
@@ -165,6 +248,40 @@ func getAttr(n *html.Node, key string) string {
 	return ""
 }
 
+// decodeEntities decodes numeric character references left as text
+// outside code. blackfriday's smartypants escapes them, so "&#8212;" in
+// markdown reaches the parsed HTML as literal text.
+//
+// Parameters:
+//   - n: Element whose descendants are decoded
+func decodeEntities(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.TextNode && strings.Contains(c.Data, "&#"):
+			c.Data = numericEntity.ReplaceAllStringFunc(c.Data, html.UnescapeString)
+		case c.Type == html.ElementNode && c.Data != "code" && c.Data != "pre":
+			decodeEntities(c)
+		}
+	}
+}
+
+// hasClass reports whether an element's class attribute lists a class.
+//
+// Parameters:
+//   - n: The element to check
+//   - class: Class name to look for
+//
+// Returns:
+//   - bool: true if class is one of the element's classes
+func hasClass(n *html.Node, class string) bool {
+	for _, name := range strings.Fields(getAttr(n, "class")) {
+		if name == class {
+			return true
+		}
+	}
+	return false
+}
+
 // getTextContent extracts all text content from an HTML node tree.
 // Concatenates text from all TextNode descendants in document order.
 //
@@ -214,3 +331,97 @@ func isJPEGImage(src string) bool {
 	return strings.HasSuffix(src, jpgExtension) ||
 		strings.HasSuffix(src, jpegExtension)
 }
+
+// romanNumerals lists Roman numeral symbols with their values in
+// descending order, including subtractive pairs.
+var romanNumerals = []struct {
+	value  int
+	symbol string
+}{
+	{1000, "m"}, {900, "cm"}, {500, "d"}, {400, "cd"},
+	{100, "c"}, {90, "xc"}, {50, "l"}, {40, "xl"},
+	{10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"},
+}
+
+// toRoman formats a positive integer as a lowercase Roman numeral.
+//
+// Parameters:
+//   - n: The number to format. Values below 1 return an empty string.
+//
+// Returns:
+//   - The lowercase Roman numeral (e.g., 4 -> "iv", 12 -> "xii")
+func toRoman(n int) string {
+	var result strings.Builder
+	for _, numeral := range romanNumerals {
+		for n >= numeral.value {
+			result.WriteString(numeral.symbol)
+			n -= numeral.value
+		}
+	}
+	return result.String()
+}
+
+// appendixLetter returns the letter label for a zero-based appendix index.
+// Letters continue as "AA", "AB", ... after "Z".
+//
+// Parameters:
+//   - index: Zero-based appendix position
+//
+// Returns:
+//   - The appendix letter label (e.g., 0 -> "A", 27 -> "AB")
+func appendixLetter(index int) string {
+	label := ""
+	for index >= 0 {
+		label = string(rune('A'+index%26)) + label
+		index = index/26 - 1
+	}
+	return label
+}
+
+// inlineElements lists the HTML elements rendered within a line of text.
+var inlineElements = map[string]bool{
+	"a": true, "em": true, "i": true, "strong": true, "b": true, "u": true,
+	"code": true, "span": true, "cite": true, "img": true, "del": true, "s": true,
+	"mark": true, "sub": true, "sup": true, "br": true,
+}
+
+// isInline reports whether n is text, a comment, or an inline element, i.e. whether
+// whitespace next to it separates words on the same line.
+//
+// Parameters:
+//   - n: The node to check. Nil returns false.
+//
+// Returns:
+//   - bool: true for text nodes and inline elements
+func isInline(n *html.Node) bool {
+	if n == nil {
+		return false
+	}
+	switch n.Type {
+	case html.TextNode, html.CommentNode: // comments carry index markers within text
+		return true
+	case html.ElementNode:
+		return inlineElements[n.Data]
+	}
+	return false
+}
+
+// isWhitespaceText reports whether n is a text node holding only
+// whitespace.
+func isWhitespaceText(n *html.Node) bool {
+	return n.Type == html.TextNode && strings.TrimSpace(n.Data) == ""
+}
+
+// isHeading reports whether n is a heading element (h1-h6).
+//
+// Parameters:
+//   - n: The node to check
+//
+// Returns:
+//   - bool: true for h1 through h6 elements
+func isHeading(n *html.Node) bool {
+	if n.Type != html.ElementNode || len(n.Data) != 2 || n.Data[0] != 'h' {
+		return false
+	}
+	return n.Data[1] >= '1' && n.Data[1] <= '6'
+}